@@ -0,0 +1,96 @@
+package dedupe
+
+import (
+	"fmt"
+
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/jippi/dottie/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+func New() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "dedupe",
+		Short:   "Remove duplicate assignments of the same KEY",
+		GroupID: "manipulate",
+		Args:    cobra.ExactArgs(0),
+		RunE:    runE,
+	}
+
+	cmd.Flags().String("keep", "enabled", "Which assignment of a duplicate KEY to keep (first, last, enabled)")
+	cmd.Flags().Bool("dry-run", false, "Print the duplicates that would be removed, but do not save the file")
+
+	return cmd
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	filename := cmd.Flag("file").Value.String()
+
+	policy, err := ast.DedupePolicyFromString(shared.StringFlag(cmd.Flags(), "keep"))
+	if err != nil {
+		return err
+	}
+
+	unlock, err := pkg.Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	document, err := pkg.Load(cmd.Context(), filename)
+	if err != nil {
+		return err
+	}
+
+	stdout := tui.StdoutFromContext(cmd.Context())
+
+	duplicates := document.Duplicates()
+	if len(duplicates) == 0 {
+		stdout.Success().Println("No duplicate keys found")
+
+		return nil
+	}
+
+	for _, duplicate := range duplicates {
+		keep := duplicate.Keep(policy)
+
+		stdout.NoColor().Printfln("Key [ %s ] is assigned %d times", duplicate.Name, len(duplicate.Assignments))
+
+		for _, assignment := range duplicate.Assignments {
+			if assignment == keep {
+				stdout.Success().Printfln("  keep   %s", describe(assignment))
+
+				continue
+			}
+
+			stdout.Danger().Printfln("  remove %s", describe(assignment))
+		}
+	}
+
+	if shared.BoolFlag(cmd.Flags(), "dry-run") {
+		stdout.Warning().Println("[--dry-run] was provided, not saving file")
+
+		return nil
+	}
+
+	document.Dedupe(policy)
+	document.Initialize(cmd.Context())
+
+	if err := pkg.Save(cmd.Context(), filename, document); err != nil {
+		return fmt.Errorf("could not save file: %w", err)
+	}
+
+	stdout.Success().Printfln("Removed duplicates of %d keys (keeping %s)", len(duplicates), policy)
+
+	return nil
+}
+
+func describe(assignment *ast.Assignment) string {
+	if !assignment.Enabled {
+		return assignment.Position.String() + " (disabled)"
+	}
+
+	return assignment.Position.String()
+}
@@ -0,0 +1,13 @@
+package dedupe_test
+
+import (
+	"testing"
+
+	"github.com/jippi/dottie/pkg/test_helpers"
+)
+
+func TestDedupeCommand(t *testing.T) {
+	t.Parallel()
+
+	test_helpers.RunFileBasedCommandTests(t, 0, "dedupe")
+}
@@ -0,0 +1,214 @@
+package diff_cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/ast/diff"
+	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/jippi/dottie/pkg/source"
+	"github.com/jippi/dottie/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+type jsonOutput struct {
+	Old   string `json:"old"`
+	New   string `json:"new"`
+	Equal bool   `json:"equal"`
+	*diff.Result
+}
+
+func New() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [OLD_FILE] [NEW_FILE]",
+		Short: "Show the differences between two .env files, or a file and its source",
+		Long: `Show the differences between two .env files, or a file and its source.
+
+With two arguments, OLD_FILE is compared to NEW_FILE.
+With one argument, OLD_FILE is compared to the [--file].
+Without arguments, the source of the [--file] (from [--source] or its [@dottie/source] annotations) is compared to the [--file].
+
+The command exits with an error when the files are different.`,
+		GroupID: "output",
+		Args:    cobra.RangeArgs(0, 2),
+		RunE:    runE,
+	}
+
+	cmd.Flags().StringArray("source", []string{}, "URL or local file path to the upstream source file, used when no files are provided. Can be repeated to merge multiple sources in order. This will take precedence over any [@dottie/source] annotations in the file")
+	cmd.Flags().Bool("json", false, "Print the differences as JSON")
+
+	shared.BoolWithInverse(cmd, "color", true, "Enable color output", "Disable color output")
+
+	return cmd
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	oldFile, newFile, err := load(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	result := diff.Documents(oldFile.document, newFile.document)
+
+	oldName, newName := oldFile.name, newFile.name
+
+	if shared.BoolFlag(cmd.Flags(), "json") {
+		output, err := json.MarshalIndent(jsonOutput{
+			Old:    oldName,
+			New:    newName,
+			Equal:  result.Equal(),
+			Result: result,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), string(output))
+	} else {
+		printResult(cmd, oldName, newName, result)
+	}
+
+	if !result.Equal() {
+		return errors.New("the files are different")
+	}
+
+	return nil
+}
+
+// file is a loaded document and the name it should be presented with
+type file struct {
+	name     string
+	document *ast.Document
+}
+
+// load returns the old and new file to compare, depending on the number of [args]
+func load(cmd *cobra.Command, args []string) (*file, *file, error) {
+	filename := cmd.Flag("file").Value.String()
+
+	switch len(args) {
+	case 2:
+		return loadFiles(cmd, args[0], args[1])
+
+	case 1:
+		return loadFiles(cmd, args[0], filename)
+	}
+
+	newDocument, err := pkg.Load(cmd.Context(), filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources, _ := cmd.Flags().GetStringArray("source")
+	if len(sources) == 0 {
+		if _, err := newDocument.GetConfig(source.Annotation); err != nil {
+			return nil, nil, fmt.Errorf("no files were provided, and [ %s ] has no source: %w", filename, err)
+		}
+
+		sources = newDocument.GetConfigs(source.Annotation)
+	}
+
+	layers := make([]*source.Layer, 0, len(sources))
+
+	for _, sourceURL := range sources {
+		layer, err := source.FetchLayer(cmd.Context(), sourceURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not load source [ %s ]: %w", sourceURL, err)
+		}
+
+		layers = append(layers, layer)
+	}
+
+	oldDocument, _, err := source.Merge(cmd.Context(), layers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &file{name: strings.Join(sources, " + "), document: oldDocument}, &file{name: filename, document: newDocument}, nil
+}
+
+func loadFiles(cmd *cobra.Command, oldFile, newFile string) (*file, *file, error) {
+	oldDocument, err := pkg.Load(cmd.Context(), oldFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newDocument, err := pkg.Load(cmd.Context(), newFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &file{name: oldFile, document: oldDocument}, &file{name: newFile, document: newDocument}, nil
+}
+
+func printResult(cmd *cobra.Command, oldName, newName string, result *diff.Result) {
+	stdout := tui.StdoutFromContext(cmd.Context())
+
+	danger, success, warning, noColor := stdout.Danger(), stdout.Success(), stdout.Warning(), stdout.NoColor()
+	if !shared.ColorEnabled(cmd.Flags(), "color") {
+		danger, success, warning = noColor, noColor, noColor
+	}
+
+	if result.Equal() {
+		success.Println("No differences found")
+
+		return
+	}
+
+	danger.Println("---", oldName)
+	success.Println("+++", newName)
+	noColor.Println()
+
+	counts := map[diff.Status]int{}
+
+	for _, key := range result.Keys {
+		counts[key.Status]++
+
+		switch key.Status {
+		case diff.Added:
+			success.Println("+", describe(key.New))
+
+		case diff.Removed:
+			danger.Println("-", describe(key.Old))
+
+		case diff.Changed:
+			warning.Println("~", key.Key)
+
+			for _, change := range key.Changes {
+				noColor.Printfln("    %s:", change.Field)
+
+				for _, line := range lines(change.Old) {
+					danger.Println("      -", line)
+				}
+
+				for _, line := range lines(change.New) {
+					success.Println("      +", line)
+				}
+			}
+		}
+	}
+
+	noColor.Println()
+	noColor.Printfln("%d added, %d removed, %d changed", counts[diff.Added], counts[diff.Removed], counts[diff.Changed])
+}
+
+func describe(assignment *ast.Assignment) string {
+	out := assignment.Name + "=" + diff.Value(assignment)
+
+	if !assignment.Enabled {
+		out += " (disabled)"
+	}
+
+	return out
+}
+
+func lines(value string) []string {
+	if len(value) == 0 {
+		return []string{"(none)"}
+	}
+
+	return strings.Split(value, "\n")
+}
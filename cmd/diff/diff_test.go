@@ -0,0 +1,13 @@
+package diff_cmd_test
+
+import (
+	"testing"
+
+	"github.com/jippi/dottie/pkg/test_helpers"
+)
+
+func TestDiffCommand(t *testing.T) {
+	t.Parallel()
+
+	test_helpers.RunFileBasedCommandTests(t, test_helpers.ReadOnly, "diff")
+}
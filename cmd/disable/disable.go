@@ -22,6 +22,12 @@ func New() *cobra.Command {
 
 			filename := cmd.Flag("file").Value.String()
 
+			unlock, err := pkg.Lock(filename)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			env, err := pkg.Load(cmd.Context(), filename)
 			if err != nil {
 				return err
@@ -20,6 +20,12 @@ func New() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			filename := cmd.Flag("file").Value.String()
 
+			unlock, err := pkg.Lock(filename)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			document, err := pkg.Load(cmd.Context(), filename)
 			if err != nil {
 				return err
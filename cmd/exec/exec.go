@@ -92,6 +92,9 @@ func Run(ctx context.Context, opts RunOptions) error {
 	errOut := tui.StderrFromContext(ctx)
 	count := 0
 
+	// The assignments updated with their command output, applied to the file once all commands have completed
+	var outputs []*ast.Assignment
+
 	for _, assignment := range document.AllAssignments(selectors...) {
 		annotations := assignment.Annotation("dottie/exec")
 		if len(annotations) == 0 {
@@ -122,7 +125,8 @@ func Run(ctx context.Context, opts RunOptions) error {
 		}
 
 		runner.Env = template.EnvironmentHelper{
-			Resolver:            document.InterpolationMapper(assignment),
+			Resolver:            document.InterpolationMapper(ctx, assignment),
+			LookupEnv:           os.LookupEnv,
 			AccessibleVariables: document.AccessibleVariables(assignment),
 			MissingKeyCallback:  template.DefaultMissingKeyCallback(ctx, assignment.Literal),
 		}
@@ -151,6 +155,8 @@ func Run(ctx context.Context, opts RunOptions) error {
 		// Update literal
 		assignment.SetLiteral(ctx, output)
 
+		outputs = append(outputs, assignment)
+
 		// Validate the assignment
 		validationErrors, err := document.ValidateSingleAssignment(ctx, assignment, nil, opts.IgnoreRules)
 		if err != nil {
@@ -183,7 +189,7 @@ func Run(ctx context.Context, opts RunOptions) error {
 		return nil
 	}
 
-	if err := pkg.Save(ctx, opts.Filename, document); err != nil {
+	if err := save(ctx, opts.Filename, outputs); err != nil {
 		return err
 	}
 
@@ -191,3 +197,29 @@ func Run(ctx context.Context, opts RunOptions) error {
 
 	return nil
 }
+
+// save applies the command [outputs] to the file. The commands run without holding the lock, since
+// they might run dottie on the same file, so it's loaded again to keep changes made in the meantime.
+func save(ctx context.Context, filename string, outputs []*ast.Assignment) error {
+	unlock, err := pkg.Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	document, err := pkg.Load(ctx, filename)
+	if err != nil {
+		return err
+	}
+
+	for _, output := range outputs {
+		assignment := document.Get(output.Name)
+		if assignment == nil {
+			return fmt.Errorf("key [ %s ] was removed from the file while running the exec commands", output.Name)
+		}
+
+		assignment.SetLiteral(ctx, output.Literal)
+	}
+
+	return pkg.Save(ctx, filename, document)
+}
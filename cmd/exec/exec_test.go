@@ -1,8 +1,17 @@
 package exec_test
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
+	"time"
 
+	exec_cmd "github.com/jippi/dottie/cmd/exec"
+	"github.com/jippi/dottie/pkg"
 	"github.com/jippi/dottie/pkg/test_helpers"
 )
 
@@ -11,3 +20,117 @@ func TestDisableCommand(t *testing.T) {
 
 	test_helpers.RunFileBasedCommandTests(t, 0, "exec")
 }
+
+// TestRunWaitsForTheLock runs exec while another process holds the lock and changes the file,
+// which is only kept if exec loads the file after the lock is released.
+func TestRunWaitsForTheLock(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(filename, []byte("# @dottie/exec echo hello\nGREETING=\nOTHER=original\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	helper := exec.Command(os.Args[0], "-test.run=^TestLockHolderProcess$")
+	helper.Env = append(os.Environ(), "DOTTIE_LOCK_HOLDER_FILE="+filename)
+
+	stdout, err := helper.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := helper.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the helper to hold the lock
+	if !bufio.NewScanner(stdout).Scan() {
+		t.Fatal("expected the helper process to report it holds the lock")
+	}
+
+	ctx := test_helpers.CreateTestContext(t, nil, nil)
+
+	if err := exec_cmd.Run(ctx, exec_cmd.RunOptions{Filename: filename, Validate: true, Save: true}); err != nil {
+		t.Fatalf("expected Run to succeed, got %v", err)
+	}
+
+	if err := helper.Wait(); err != nil {
+		t.Fatalf("helper process failed: %v", err)
+	}
+
+	doc, err := pkg.Load(context.Background(), filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := doc.Get("GREETING").Literal; got != "hello" {
+		t.Fatalf("expected GREETING to be set by exec, got %q", got)
+	}
+
+	if got := doc.Get("OTHER").Literal; got != "changed" {
+		t.Fatalf("expected the change made while the lock was held to be kept, got %q", got)
+	}
+}
+
+// TestRunDoesNotHoldTheLockWhileCommandsRun runs an exec command which itself locks and changes
+// the file, like running dottie from an annotation does, which would deadlock if exec held the lock.
+func TestRunDoesNotHoldTheLockWhileCommandsRun(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), ".env")
+	command := fmt.Sprintf("DOTTIE_LOCK_HOLDER_FILE=%s %s '-test.run=^TestLockHolderProcess$' >/dev/null && echo hello", filename, os.Args[0])
+
+	if err := os.WriteFile(filename, []byte("# @dottie/exec "+command+"\nGREETING=\nOTHER=original\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := test_helpers.CreateTestContext(t, nil, nil)
+
+	if err := exec_cmd.Run(ctx, exec_cmd.RunOptions{Filename: filename, Validate: true, Save: true}); err != nil {
+		t.Fatalf("expected Run to succeed, got %v", err)
+	}
+
+	doc, err := pkg.Load(context.Background(), filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := doc.Get("GREETING").Literal; got != "hello" {
+		t.Fatalf("expected GREETING to be set by exec, got %q", got)
+	}
+
+	if got := doc.Get("OTHER").Literal; got != "changed" {
+		t.Fatalf("expected the change made by the command to be kept, got %q", got)
+	}
+}
+
+func TestLockHolderProcess(t *testing.T) {
+	filename := os.Getenv("DOTTIE_LOCK_HOLDER_FILE")
+	if filename == "" {
+		t.Skip("only used as a helper process by the exec lock tests")
+	}
+
+	ctx := context.Background()
+
+	unlock, err := pkg.Lock(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	doc, err := pkg.Load(ctx, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Stdout.WriteString("locked\n")
+
+	// Give the exec command time to try loading the file while the lock is held
+	time.Sleep(500 * time.Millisecond)
+
+	doc.Get("OTHER").SetLiteral(ctx, "changed")
+
+	if err := pkg.Save(ctx, filename, doc); err != nil {
+		t.Fatal(err)
+	}
+}
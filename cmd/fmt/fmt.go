@@ -15,12 +15,18 @@ func New() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			filename := cmd.Flag("file").Value.String()
 
+			unlock, err := pkg.Lock(filename)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
 			document, err := pkg.Load(cmd.Context(), filename)
 			if err != nil {
 				return err
 			}
 
-			if err := pkg.Save(cmd.Context(), filename, document); err != nil {
+			if err := pkg.SaveFormatted(cmd.Context(), filename, document); err != nil {
 				return err
 			}
 
@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/spf13/cobra"
+)
+
+func New() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "graph",
+		Short:   "Print the graph of KEYs referencing each other during interpolation",
+		Args:    cobra.ExactArgs(0),
+		GroupID: "output",
+		RunE:    runE,
+	}
+
+	cmd.Flags().String("format", "dot", "Output format: dot (Graphviz), mermaid or json")
+	cmd.Flags().String("key", "", "Only show the KEYs this KEY depends on, and the KEYs depending on it")
+
+	_ = cmd.RegisterFlagCompletionFunc("key", shared.NewCompleter().Get())
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"dot", "mermaid", "json"}, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	filename := cmd.Flag("file").Value.String()
+
+	document, err := pkg.Load(cmd.Context(), filename)
+	if err != nil {
+		return err
+	}
+
+	graph := document.Graph()
+
+	if key := shared.StringFlag(cmd.Flags(), "key"); len(key) > 0 {
+		graph, err = graph.Neighborhood(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch format := shared.StringFlag(cmd.Flags(), "format"); format {
+	case "dot":
+		writeDot(cmd.OutOrStdout(), graph)
+
+	case "mermaid":
+		writeMermaid(cmd.OutOrStdout(), graph)
+
+	case "json":
+		output, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), string(output))
+
+	default:
+		return fmt.Errorf("invalid [--format] value [ %s ], must be one of: dot, mermaid, json", format)
+	}
+
+	return nil
+}
+
+// writeDot writes the [graph] in the Graphviz DOT format
+func writeDot(out io.Writer, graph *ast.Graph) {
+	fmt.Fprintln(out, "digraph dottie {")
+	fmt.Fprintln(out, "  rankdir=LR;")
+	fmt.Fprintln(out, "  node [shape=box];")
+
+	for _, node := range graph.Nodes {
+		var attributes []string
+
+		switch {
+		case node.Missing:
+			attributes = append(attributes, `label="`+node.Name+` (missing)"`, "style=dashed", "color=orange")
+
+		case !node.Enabled:
+			attributes = append(attributes, `label="`+node.Name+` (disabled)"`, "style=dashed", "color=gray", "fontcolor=gray")
+		}
+
+		if node.Cycle {
+			attributes = append(attributes, "color=red", "fontcolor=red")
+		}
+
+		fmt.Fprintf(out, "  %q%s;\n", node.Name, dotAttributes(attributes))
+	}
+
+	for _, edge := range graph.Edges {
+		var attributes []string
+
+		switch {
+		case edge.Cycle:
+			attributes = append(attributes, "color=red")
+
+		case edge.Missing:
+			attributes = append(attributes, "style=dashed", "color=orange")
+
+		case edge.Disabled:
+			attributes = append(attributes, "style=dashed", "color=gray")
+		}
+
+		fmt.Fprintf(out, "  %q -> %q%s;\n", edge.From, edge.To, dotAttributes(attributes))
+	}
+
+	fmt.Fprintln(out, "}")
+}
+
+func dotAttributes(attributes []string) string {
+	if len(attributes) == 0 {
+		return ""
+	}
+
+	return " [" + strings.Join(attributes, ", ") + "]"
+}
+
+// writeMermaid writes the [graph] as a Mermaid flowchart
+func writeMermaid(out io.Writer, graph *ast.Graph) {
+	// Node IDs are generated, since KEYs like "end" are reserved words in Mermaid
+	ids := map[string]string{}
+
+	fmt.Fprintln(out, "flowchart LR")
+
+	for i, node := range graph.Nodes {
+		ids[node.Name] = fmt.Sprintf("n%d", i)
+
+		label := node.Name
+		class := ""
+
+		switch {
+		case node.Missing:
+			label += " (missing)"
+			class = ":::missing"
+
+		case !node.Enabled:
+			label += " (disabled)"
+			class = ":::disabled"
+		}
+
+		if node.Cycle {
+			class = ":::cycle"
+		}
+
+		fmt.Fprintf(out, "  %s[\"%s\"]%s\n", ids[node.Name], label, class)
+	}
+
+	var cycleLinks []string
+
+	for i, edge := range graph.Edges {
+		arrow := "-->"
+		if edge.Missing || edge.Disabled {
+			arrow = "-.->"
+		}
+
+		if edge.Cycle {
+			cycleLinks = append(cycleLinks, fmt.Sprint(i))
+		}
+
+		fmt.Fprintf(out, "  %s %s %s\n", ids[edge.From], arrow, ids[edge.To])
+	}
+
+	fmt.Fprintln(out, "  classDef missing stroke:orange,stroke-dasharray:5 5")
+	fmt.Fprintln(out, "  classDef disabled stroke:gray,color:gray,stroke-dasharray:5 5")
+	fmt.Fprintln(out, "  classDef cycle stroke:red,color:red")
+
+	if len(cycleLinks) > 0 {
+		fmt.Fprintf(out, "  linkStyle %s stroke:red\n", strings.Join(cycleLinks, ","))
+	}
+}
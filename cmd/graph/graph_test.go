@@ -0,0 +1,13 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/jippi/dottie/pkg/test_helpers"
+)
+
+func TestGraphCommand(t *testing.T) {
+	t.Parallel()
+
+	test_helpers.RunFileBasedCommandTests(t, test_helpers.ReadOnly, "graph")
+}
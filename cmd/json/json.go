@@ -5,11 +5,13 @@ import (
 	"fmt"
 
 	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/jippi/dottie/pkg/template"
 	"github.com/spf13/cobra"
 )
 
 func New() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:     "json",
 		Short:   "Print as JSON",
 		Args:    cobra.ExactArgs(0),
@@ -17,11 +19,18 @@ func New() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			filename := cmd.Flag("file").Value.String()
 
-			document, err := pkg.Load(cmd.Context(), filename)
+			document, _, err := pkg.LoadLayered(cmd.Context(), filename)
 			if err != nil {
 				return err
 			}
 
+			// Strict mode interpolates the values, so references to unset variables fail before anything is printed
+			if shared.BoolFlag(cmd.Flags(), "strict") {
+				if err := document.InterpolateAll(template.ContextWithStrict(cmd.Context(), true)); err != nil {
+					return err
+				}
+			}
+
 			output, err := json.MarshalIndent(document, "", "  ")
 			if err != nil {
 				return err
@@ -32,4 +41,8 @@ func New() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().Bool("strict", false, "Interpolate values, and fail on references to unset variables without a default value")
+
+	return cmd
 }
@@ -37,6 +37,27 @@ func TestJsonCommandOutputsDocumentAsJSON(t *testing.T) {
 	assert.Empty(t, stderr.String())
 }
 
+func TestJsonCommandOutputsInlineComment(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	envFile := filepath.Join(tempDir, ".env")
+
+	require.NoError(t, os.WriteFile(envFile, []byte("PORT=3306 # default mysql port\n"), 0o600))
+
+	var stdout bytes.Buffer
+
+	var stderr bytes.Buffer
+
+	ctx := test_helpers.CreateTestContext(t, &stdout, &stderr)
+	_, err := cmd.RunCommand(ctx, []string{"json", "--file", envFile}, &stdout, &stderr)
+
+	require.NoError(t, err)
+	assert.Contains(t, stdout.String(), "\"literal\": \"3306\"")
+	assert.Contains(t, stdout.String(), "\"inline_comment\": \"# default mysql port\"")
+	assert.Empty(t, stderr.String())
+}
+
 func TestJsonCommandRejectsExtraArgs(t *testing.T) {
 	t.Parallel()
 
@@ -74,3 +95,22 @@ func TestJsonCommandErrorsWhenFileIsMissing(t *testing.T) {
 	assert.Contains(t, stderr.String(), "Run 'dottie json --help' for usage.")
 	assert.Empty(t, stdout.String())
 }
+
+func TestJsonCommandStrictFailsOnUnsetVariables(t *testing.T) {
+	t.Parallel()
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+
+	require.NoError(t, os.WriteFile(envFile, []byte("URL=\"https://${HOST}\"\nHOST=example.com\n"), 0o600))
+
+	var stdout bytes.Buffer
+
+	var stderr bytes.Buffer
+
+	ctx := test_helpers.CreateTestContext(t, &stdout, &stderr)
+	_, err := cmd.RunCommand(ctx, []string{"json", "--strict", "--file", envFile}, &stdout, &stderr)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "required variable HOST is missing a value")
+	assert.Empty(t, stdout.String())
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/jippi/dottie/pkg/ast"
 	"github.com/jippi/dottie/pkg/cli/shared"
 	"github.com/jippi/dottie/pkg/render"
+	"github.com/jippi/dottie/pkg/template"
 	"github.com/jippi/dottie/pkg/tui"
 	"github.com/spf13/cobra"
 	"go.uber.org/multierr"
@@ -22,7 +23,7 @@ func New() *cobra.Command {
 	}
 
 	cmd.Flags().Bool("pretty", false, "implies --color --comments --blank-lines --group-banners")
-	cmd.Flags().Bool("export", false, "prefix all key/value pairs with [export] statement")
+	cmd.Flags().Bool("export", false, "prefix all key/value pairs with [export] statement (use [--export=false] to remove it from all pairs)")
 	cmd.Flags().Bool("with-disabled", false, "Include disabled assignments")
 
 	cmd.Flags().String("key-prefix", "", "Filter by key prefix")
@@ -34,10 +35,14 @@ func New() *cobra.Command {
 	shared.BoolWithInverse(cmd, "group-banners", false, "Show group banners", "Do not show group banners")
 	shared.BoolWithInverse(cmd, "interpolation", true, "Enable interpolation", "Disable interpolation")
 
+	cmd.Flags().Bool("strict", false, "Fail on references to unset variables without a default value")
+
 	return cmd
 }
 
 func runE(cmd *cobra.Command, args []string) error {
+	cmd.SetContext(template.ContextWithStrict(cmd.Context(), shared.BoolFlag(cmd.Flags(), "strict")))
+
 	document, settings, err := setup(cmd)
 	if err != nil {
 		return err
@@ -68,7 +73,7 @@ func setup(cmd *cobra.Command) (*ast.Document, *render.Settings, error) {
 		return shared.StringFlag(flags, name)
 	}
 
-	doc, err := pkg.Load(cmd.Context(), stringFlag("file"))
+	doc, _, err := pkg.LoadLayered(cmd.Context(), stringFlag("file"))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -94,6 +99,8 @@ func setup(cmd *cobra.Command) (*ast.Document, *render.Settings, error) {
 				allErrors = multierr.Append(allErrors, err)
 			}
 		}
+
+		allErrors = ast.UniqueErrors(allErrors)
 	}
 
 	if boolFlag("pretty") {
@@ -102,8 +109,8 @@ func setup(cmd *cobra.Command) (*ast.Document, *render.Settings, error) {
 
 	settings.Apply(render.WithColors(shouldColorOutput(cmd)))
 
-	if boolFlag("export") {
-		settings.Apply(render.WithExport(true))
+	if flags.Changed("export") {
+		settings.Apply(render.WithExport(boolFlag("export")))
 	}
 
 	if cmd.Flags().NArg() > 0 {
@@ -0,0 +1,84 @@
+package rename
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/jippi/dottie/pkg/render"
+	"github.com/jippi/dottie/pkg/tui"
+	"github.com/spf13/cobra"
+)
+
+func New() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "rename OLD NEW",
+		Short:             "Rename a KEY and rewrite all references to it",
+		GroupID:           "manipulate",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: shared.NewCompleter().Get(),
+		RunE:              runE,
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Print the lines that would change, but do not save the file")
+
+	return cmd
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	filename := cmd.Flag("file").Value.String()
+
+	unlock, err := pkg.Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	document, err := pkg.Load(cmd.Context(), filename)
+	if err != nil {
+		return err
+	}
+
+	oldName, newName := args[0], args[1]
+
+	// Capture how the assignments look before renaming, so we can show what changed
+	before := map[*ast.Assignment]string{}
+
+	for _, assignment := range document.AllAssignments() {
+		before[assignment] = renderLine(cmd, assignment)
+	}
+
+	changed, err := document.Rename(cmd.Context(), oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	stdout := tui.StdoutFromContext(cmd.Context())
+	dryRun := shared.BoolFlag(cmd.Flags(), "dry-run")
+
+	for _, assignment := range changed {
+		stdout.NoColor().Println(assignment.Position)
+		stdout.Danger().Println("  -", before[assignment])
+		stdout.Success().Println("  +", renderLine(cmd, assignment))
+	}
+
+	if dryRun {
+		stdout.Warning().Println("[--dry-run] was provided, not saving file")
+
+		return nil
+	}
+
+	if err := pkg.Save(cmd.Context(), filename, document); err != nil {
+		return fmt.Errorf("could not save file: %w", err)
+	}
+
+	stdout.Success().Printfln("Key [ %s ] was successfully renamed to [ %s ] (%d references updated)", oldName, newName, len(changed)-1)
+
+	return nil
+}
+
+func renderLine(cmd *cobra.Command, assignment *ast.Assignment) string {
+	return strings.TrimSpace(render.PlainOutput{}.Assignment(cmd.Context(), assignment, render.Settings{}).String())
+}
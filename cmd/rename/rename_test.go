@@ -0,0 +1,13 @@
+package rename_test
+
+import (
+	"testing"
+
+	"github.com/jippi/dottie/pkg/test_helpers"
+)
+
+func TestRenameCommand(t *testing.T) {
+	t.Parallel()
+
+	test_helpers.RunFileBasedCommandTests(t, 0, "rename")
+}
@@ -2,23 +2,35 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 
 	goversion "github.com/caarlos0/go-version"
+	dedupe_cmd "github.com/jippi/dottie/cmd/dedupe"
+	diff_cmd "github.com/jippi/dottie/cmd/diff"
 	disable_cmd "github.com/jippi/dottie/cmd/disable"
 	enable_cmd "github.com/jippi/dottie/cmd/enable"
 	exec_cmd "github.com/jippi/dottie/cmd/exec"
 	fmt_cmd "github.com/jippi/dottie/cmd/fmt"
+	graph_cmd "github.com/jippi/dottie/cmd/graph"
 	groups_cmd "github.com/jippi/dottie/cmd/groups"
 	json_cmd "github.com/jippi/dottie/cmd/json"
 	print_cmd "github.com/jippi/dottie/cmd/print"
+	rename_cmd "github.com/jippi/dottie/cmd/rename"
 	set_cmd "github.com/jippi/dottie/cmd/set"
 	shell_cmd "github.com/jippi/dottie/cmd/shell"
 	template_cmd "github.com/jippi/dottie/cmd/template"
+	unset_cmd "github.com/jippi/dottie/cmd/unset"
 	update_cmd "github.com/jippi/dottie/cmd/update"
 	validate_cmd "github.com/jippi/dottie/cmd/validate"
 	value_cmd "github.com/jippi/dottie/cmd/value"
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/jippi/dottie/pkg/scanner"
+	"github.com/jippi/dottie/pkg/template"
 	"github.com/jippi/dottie/pkg/tui"
 	"github.com/spf13/cobra"
 )
@@ -42,6 +54,9 @@ func NewRootCommand() *cobra.Command {
 	root.AddGroup(&cobra.Group{ID: "output", Title: "Output Commands"})
 
 	root.AddCommand(set_cmd.New())
+	root.AddCommand(unset_cmd.New())
+	root.AddCommand(rename_cmd.New())
+	root.AddCommand(dedupe_cmd.New())
 	root.AddCommand(update_cmd.New())
 	root.AddCommand(fmt_cmd.New())
 	root.AddCommand(disable_cmd.New())
@@ -51,9 +66,11 @@ func NewRootCommand() *cobra.Command {
 
 	root.AddCommand(print_cmd.New())
 	root.AddCommand(validate_cmd.New())
+	root.AddCommand(diff_cmd.New())
 	root.AddCommand(value_cmd.New())
 	root.AddCommand(groups_cmd.New())
 	root.AddCommand(json_cmd.New())
+	root.AddCommand(graph_cmd.New())
 	root.AddCommand(template_cmd.New())
 
 	return root
@@ -68,9 +85,71 @@ func RunCommand(ctx context.Context, args []string, stdout, stderr io.Writer) (*
 	root.SetErr(stderr)
 	root.SetOut(stdout)
 	root.PersistentFlags().StringP("file", "f", ".env", "Load this file")
+	root.PersistentFlags().Bool("preserve-formatting", false, "Keep the original formatting of the file when saving, only changed KEY=VALUE pairs are rewritten")
+	root.PersistentFlags().String("mode", "", "Merge the layered env files for this mode on top of FILE, like Vite and Next.js do (FILE, FILE.local, FILE.<mode>, FILE.<mode>.local), for print, value, json and validate")
+	root.PersistentFlags().StringArray("layer", []string{}, "Merge this env file on top of FILE (after the [--mode] files), can be repeated, for print, value, json and validate")
+	root.PersistentFlags().String("env-policy", "", "How interpolation uses the process environment: env-first, file-first, file-only or allow:NAME[,NAME...] (by default the [@dottie/env-policy] annotation, or env-first)")
+	root.PersistentFlags().Int("max-input-bytes", scanner.DefaultLimits.MaxInputBytes, "The largest file (in bytes) that will be read, 0 means unlimited")
+	root.PersistentFlags().Int("max-value-bytes", scanner.DefaultLimits.MaxValueBytes, "The largest single value (in bytes) that will be read, 0 means unlimited")
+	root.PersistentFlags().Bool("allow-command-substitution", false, "Execute $(...) command substitutions in values during interpolation, instead of keeping them verbatim (only for trusted files!)")
+	root.PersistentFlags().Duration("command-timeout", template.DefaultCommandTimeout, "How long each command substitution may run, when [--allow-command-substitution] is used")
+
+	var commandSubstitution *template.CommandSubstitution
+
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		ctx := pkg.WithPreserveFormatting(cmd.Context(), shared.BoolFlag(cmd.Flags(), "preserve-formatting"))
+
+		// Files (including remote update sources) are read within the default limits, unless they are explicitly raised
+		if cmd.Flags().Changed("max-input-bytes") || cmd.Flags().Changed("max-value-bytes") {
+			limits := scanner.DefaultLimits
+			limits.MaxInputBytes, _ = cmd.Flags().GetInt("max-input-bytes")
+			limits.MaxValueBytes, _ = cmd.Flags().GetInt("max-value-bytes")
+
+			if limits.MaxInputBytes < 0 || limits.MaxValueBytes < 0 {
+				return errors.New("[--max-input-bytes] and [--max-value-bytes] must not be negative")
+			}
+
+			ctx = scanner.ContextWithLimits(ctx, limits)
+		}
+
+		if value := shared.StringFlag(cmd.Flags(), "env-policy"); len(value) > 0 {
+			policy, err := ast.EnvPolicyFromString(value)
+			if err != nil {
+				return err
+			}
+
+			ctx = ast.ContextWithEnvPolicy(ctx, policy)
+		}
+
+		mode := shared.StringFlag(cmd.Flags(), "mode")
+		layers, _ := cmd.Flags().GetStringArray("layer")
+
+		if len(mode) > 0 || len(layers) > 0 {
+			ctx = pkg.ContextWithLayers(ctx, pkg.Layers{Mode: mode, Files: layers})
+		}
+
+		if shared.BoolFlag(cmd.Flags(), "allow-command-substitution") {
+			timeout, err := cmd.Flags().GetDuration("command-timeout")
+			if err != nil {
+				return err
+			}
+
+			commandSubstitution = &template.CommandSubstitution{Timeout: timeout}
+			ctx = template.ContextWithCommandSubstitution(ctx, commandSubstitution)
+		}
+
+		cmd.SetContext(ctx)
+
+		return nil
+	}
 	root.SetVersionTemplate(`{{ .Version }}`)
 
 	command, err := root.ExecuteC()
+
+	if commandSubstitution != nil {
+		printExecutedCommands(tui.WriterFromContext(ctx, tui.Stderr), commandSubstitution.Executed())
+	}
+
 	if err != nil {
 		stderr := tui.WriterFromContext(ctx, tui.Stderr)
 		stderr.Danger().Copy(tui.WithEmphasis(true)).Printfln("%s %+v", command.ErrPrefix(), err)
@@ -80,6 +159,45 @@ func RunCommand(ctx context.Context, args []string, stdout, stderr io.Writer) (*
 	return command, err
 }
 
+// printExecutedCommands warns about every command that was executed by [--allow-command-substitution]
+func printExecutedCommands(stderr tui.Writer, commands []string) {
+	if len(commands) == 0 {
+		return
+	}
+
+	var (
+		unique []string
+		counts = map[string]int{}
+	)
+
+	for _, command := range commands {
+		if counts[command] == 0 {
+			unique = append(unique, command)
+		}
+
+		counts[command]++
+	}
+
+	header := fmt.Sprintf("%d commands were executed by command substitution", len(commands))
+	if len(commands) == 1 {
+		header = "1 command was executed by command substitution"
+	}
+
+	stderr.Warning().Box(header)
+
+	for _, command := range unique {
+		if counts[command] > 1 {
+			stderr.NoColor().Printfln("  * $(%s) (%d times)", command, counts[command])
+
+			continue
+		}
+
+		stderr.NoColor().Printfln("  * $(%s)", command)
+	}
+
+	stderr.NoColor().Println()
+}
+
 func indent(in string) string {
 	return strings.TrimSpace(strings.Join(strings.Split(in, "\n"), "\n   "))
 }
@@ -19,7 +19,7 @@ func TestNewRootCommandRegistersCoreCommands(t *testing.T) {
 		commandNames[sub.Name()] = true
 	}
 
-	for _, expected := range []string{"set", "update", "fmt", "disable", "enable", "exec", "shell", "print", "validate", "value", "groups", "json", "template"} {
+	for _, expected := range []string{"set", "unset", "rename", "dedupe", "update", "fmt", "disable", "enable", "exec", "shell", "print", "validate", "diff", "value", "groups", "json", "graph", "template"} {
 		if !commandNames[expected] {
 			t.Fatalf("expected root command to register %q", expected)
 		}
@@ -42,6 +42,7 @@ func New() *cobra.Command {
 	cmd.Flags().String("after", "", "If the key doesn't exist, add it to the file *after* this KEY")
 	cmd.Flags().String("quote-style", "double", "The quote style to use (single, double, none)")
 	cmd.Flags().StringSlice("comment", nil, "Set one or multiple lines of comments to the KEY=VALUE pair")
+	cmd.Flags().String("inline-comment", "", "Set the trailing comment on the KEY=VALUE line (use an empty string to remove it)")
 
 	cmd.MarkFlagsMutuallyExclusive("before", "after", "group")
 
@@ -51,6 +52,12 @@ func New() *cobra.Command {
 func runE(cmd *cobra.Command, args []string) error {
 	filename := cmd.Flag("file").Value.String()
 
+	unlock, err := pkg.Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	document, err := pkg.Load(cmd.Context(), filename)
 	if err != nil {
 		return err
@@ -67,11 +74,17 @@ func runE(cmd *cobra.Command, args []string) error {
 		upsert.EnableSettingIf(upsert.SkipIfExists, shared.BoolFlag(cmd.Flags(), "skip-if-exists")),
 		upsert.EnableSettingIf(upsert.SkipIfSame, shared.BoolFlag(cmd.Flags(), "skip-if-same")),
 		upsert.EnableSettingIf(upsert.UpdateComments, cmd.Flag("comment").Changed),
+		upsert.EnableSettingIf(upsert.UpdateInlineComment, cmd.Flag("inline-comment").Changed),
 	)
 	if err != nil {
 		return fmt.Errorf("error setting up upserter: %w", err)
 	}
 
+	inlineComment := shared.StringFlag(cmd.Flags(), "inline-comment")
+	if strings.ContainsAny(inlineComment, "\r\n") {
+		return errors.New("the [--inline-comment] flag may not contain new lines")
+	}
+
 	if err := upserter.ApplyOptions(upsert.WithPlacementInGroupIgnoringEmpty(upsert.AddBeforeKey, shared.StringFlag(cmd.Flags(), "before"))); err != nil {
 		return fmt.Errorf("error in processing [--before] flag: %w", err)
 	}
@@ -148,6 +161,8 @@ func runE(cmd *cobra.Command, args []string) error {
 			Interpolated: value,
 			Quote:        token.QuoteFromString(shared.StringFlag(cmd.Flags(), "quote-style")),
 			Comments:     ast.NewCommentsFromSlice(shared.StringSliceFlag(cmd.Flags(), "comment")),
+
+			InlineComment: ast.NewInlineComment(inlineComment),
 		}
 
 		assignment.SetLiteral(cmd.Context(), value)
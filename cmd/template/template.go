@@ -8,6 +8,7 @@ import (
 	"github.com/jippi/dottie/pkg"
 	"github.com/jippi/dottie/pkg/ast"
 	"github.com/jippi/dottie/pkg/cli/shared"
+	templatepkg "github.com/jippi/dottie/pkg/template"
 	"github.com/spf13/cobra"
 	"go.uber.org/multierr"
 )
@@ -23,11 +24,14 @@ func New() *cobra.Command {
 
 	shared.BoolWithInverse(cmd, "interpolation", true, "Enable interpolation", "Disable interpolation")
 	cmd.Flags().Bool("with-disabled", false, "Include disabled assignments")
+	cmd.Flags().Bool("strict", false, "Fail on references to unset variables without a default value")
 
 	return cmd
 }
 
 func runE(cmd *cobra.Command, args []string) error {
+	cmd.SetContext(templatepkg.ContextWithStrict(cmd.Context(), shared.BoolFlag(cmd.Flags(), "strict")))
+
 	document, err := setup(cmd)
 	if err != nil {
 		return err
@@ -73,5 +77,5 @@ func setup(cmd *cobra.Command) (*ast.Document, error) {
 		}
 	}
 
-	return doc, allErrors
+	return doc, ast.UniqueErrors(allErrors)
 }
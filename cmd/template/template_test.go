@@ -179,3 +179,17 @@ func TestTemplateCommandReturnsExecutionError(t *testing.T) {
 	assert.Contains(t, result.stderr, "Run 'dottie template --help' for usage.")
 	assert.Empty(t, result.stdout)
 }
+
+func TestTemplateCommandStrictFailsOnUnsetVariables(t *testing.T) {
+	t.Parallel()
+
+	withoutFlag := runTemplateCommand(t, "B=${A}\nA=hello\n", "{{ ( .Get \"B\" ).Interpolated }}")
+	withFlag := runTemplateCommand(t, "B=${A}\nA=hello\n", "{{ ( .Get \"B\" ).Interpolated }}", "--strict")
+
+	require.NoError(t, withoutFlag.err)
+	assert.Empty(t, withoutFlag.stdout)
+
+	require.Error(t, withFlag.err)
+	assert.Contains(t, withFlag.err.Error(), "required variable A is missing a value")
+	assert.Empty(t, withFlag.stdout)
+}
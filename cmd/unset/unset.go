@@ -0,0 +1,126 @@
+package unset
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/jippi/dottie/pkg/tui"
+	"github.com/spf13/cobra"
+	"go.uber.org/multierr"
+)
+
+func New() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "unset KEY [KEY ...]",
+		Short:             "Remove one or multiple KEYs (and their comments) from the file",
+		GroupID:           "manipulate",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: shared.NewCompleter().Get(),
+		RunE:              runE,
+	}
+
+	cmd.Flags().Bool("force", false, "Remove the KEY even if other KEYs reference it (a warning will be printed instead)")
+	cmd.Flags().Bool("remove-empty-groups", false, "Remove groups that no longer contain any KEYs after the removal")
+
+	return cmd
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	filename := cmd.Flag("file").Value.String()
+
+	unlock, err := pkg.Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	document, err := pkg.Load(cmd.Context(), filename)
+	if err != nil {
+		return err
+	}
+
+	var (
+		allErrors      error
+		force          = shared.BoolFlag(cmd.Flags(), "force")
+		stdout, stderr = tui.WritersFromContext(cmd.Context())
+		keys           []string
+	)
+
+	for _, key := range args {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	//
+	// Check all KEYs before changing anything, so we never save a half-done removal
+	//
+
+	for _, key := range keys {
+		assignment := document.Get(key)
+		if assignment == nil {
+			allErrors = multierr.Append(allErrors, fmt.Errorf("could not find KEY [ %s ]", key))
+
+			continue
+		}
+
+		// Dependents that are being removed in the same operation won't break
+		var dependents []string
+
+		for _, name := range slices.Sorted(maps.Keys(assignment.Dependents)) {
+			if !slices.Contains(keys, name) {
+				dependents = append(dependents, name)
+			}
+		}
+
+		if len(dependents) == 0 {
+			continue
+		}
+
+		err := fmt.Errorf("the KEY [ %s ] is referenced by [ %s ]", key, strings.Join(dependents, ", "))
+
+		if !force {
+			allErrors = multierr.Append(allErrors, fmt.Errorf("%w - use [--force] to remove it anyway", err))
+
+			continue
+		}
+
+		stderr.Warning().Println("WARNING:", err.Error())
+	}
+
+	if allErrors != nil {
+		return allErrors
+	}
+
+	//
+	// Remove the KEYs
+	//
+
+	for _, key := range keys {
+		if _, err := document.Delete(key); err != nil {
+			return err
+		}
+
+		stdout.Success().Printfln("Key [ %s ] was successfully removed", key)
+	}
+
+	if shared.BoolFlag(cmd.Flags(), "remove-empty-groups") {
+		for _, group := range document.RemoveEmptyGroups() {
+			stdout.Success().Printfln("Group [ %s ] was removed since it is now empty", group.String())
+		}
+	}
+
+	document.Initialize(cmd.Context())
+
+	if err := pkg.Save(cmd.Context(), filename, document); err != nil {
+		return fmt.Errorf("could not save file: %w", err)
+	}
+
+	stdout.Success().Println("File was successfully saved")
+
+	return nil
+}
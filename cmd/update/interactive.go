@@ -0,0 +1,159 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/go-playground/validator/v10"
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/validation"
+	"golang.org/x/term"
+)
+
+// resolution is how the user chose to resolve a [mergeConflict]
+type resolution uint
+
+const (
+	resolveKeepLocal  resolution = iota // Use the value from the FILE
+	resolveTakeSource                   // Use the value from the SOURCE
+	resolveEdit                         // Use a new value provided by the user
+	resolveDisable                      // Use the value from the FILE, but disable the KEY
+)
+
+// mergeConflict is a KEY that [update] could not merge on its own
+type mergeConflict struct {
+	reason string
+	local  *ast.Assignment
+	source *ast.Assignment // nil if the KEY is missing from the SOURCE
+}
+
+// errNotInteractive is returned when [--interactive] is used without a terminal to prompt in
+var errNotInteractive = errors.New("[--interactive] requires a terminal")
+
+func ensureInteractive() error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) { //nolint:gosec
+		return errNotInteractive
+	}
+
+	return nil
+}
+
+// resolveInteractively asks the user how to resolve the [mergeConflict].
+//
+// The returned assignment should be merged into the SOURCE document, and is nil if the SOURCE value should be kept.
+func resolveInteractively(ctx context.Context, document *ast.Document, conflict mergeConflict) (resolution, *ast.Assignment, error) {
+	choice := resolveKeepLocal
+
+	options := []huh.Option[resolution]{
+		huh.NewOption("Keep local value ["+conflict.local.Literal+"]", resolveKeepLocal),
+	}
+
+	if conflict.source != nil {
+		options = append(options, huh.NewOption("Take source value ["+conflict.source.Literal+"]", resolveTakeSource))
+	}
+
+	options = append(
+		options,
+		huh.NewOption("Edit the value", resolveEdit),
+		huh.NewOption("Disable the key", resolveDisable),
+	)
+
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewNote().
+				Title("Conflict for "+conflict.local.Name).
+				Description(describeConflict(conflict)),
+			huh.NewSelect[resolution]().
+				Title("How do you want to resolve it? (Press Ctrl+C to exit/cancel)").
+				Options(options...).
+				Value(&choice),
+		),
+	).Run()
+	if err != nil {
+		return choice, nil, err
+	}
+
+	switch choice {
+	case resolveTakeSource:
+		return choice, nil, nil
+
+	case resolveEdit:
+		value, err := askForValue(ctx, document, conflict)
+		if err != nil {
+			return choice, nil, err
+		}
+
+		edited := *conflict.local
+		edited.Literal = value
+		edited.Enabled = true
+
+		return choice, &edited, nil
+
+	case resolveDisable:
+		disabled := *conflict.local
+		disabled.Enabled = false
+
+		return choice, &disabled, nil
+
+	case resolveKeepLocal:
+	}
+
+	return choice, conflict.local, nil
+}
+
+func askForValue(ctx context.Context, document *ast.Document, conflict mergeConflict) (string, error) {
+	value := conflict.local.Literal
+
+	// The validation rules of the SOURCE take precedence, as they are the ones the merged document will have
+	rules := conflict.local
+	if conflict.source != nil {
+		rules = conflict.source
+	}
+
+	err := huh.NewInput().
+		Title("Please provide value for " + conflict.local.Name).
+		Validate(func(s string) error {
+			probe := *rules
+			probe.Literal = s
+			probe.Interpolated = s
+
+			for _, err := range validator.New().ValidateMap(map[string]any{probe.Name: s}, map[string]any{probe.Name: probe.ValidationRules()}) {
+				return errors.New(validation.Explain(ctx, document, err, &probe, false, false))
+			}
+
+			return nil
+		}).
+		Value(&value).
+		Run()
+
+	return value, err
+}
+
+func describeConflict(conflict mergeConflict) string {
+	var buf strings.Builder
+
+	fmt.Fprintln(&buf, conflict.reason)
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "Local value:  [%s]\n", conflict.local.Literal)
+
+	documentation := conflict.local.Documentation(true)
+
+	if conflict.source != nil {
+		fmt.Fprintf(&buf, "Source value: [%s]\n", conflict.source.Literal)
+
+		documentation = conflict.source.Documentation(true)
+	} else {
+		fmt.Fprintln(&buf, "Source value: (missing)")
+	}
+
+	if documentation = strings.TrimSpace(documentation); len(documentation) > 0 {
+		fmt.Fprintln(&buf)
+		fmt.Fprintln(&buf, documentation)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,42 @@
+package update
+
+import (
+	"github.com/jippi/dottie/pkg/ast"
+)
+
+// mergeDecision is the outcome of a three-way merge of a single KEY
+type mergeDecision uint
+
+const (
+	// keepLocal merges the local value into the source, like a two-way merge
+	keepLocal mergeDecision = iota
+
+	// takeSource keeps the new value from the source, because the local value was never changed from the base
+	takeSource
+
+	// conflict means both the local value and the source value were changed since the base
+	conflict
+)
+
+// threeWayMerge decides which value of a KEY to keep, using the [base] snapshot of the source from the previous update.
+//
+// Without a [base] assignment for the KEY, there is no way to tell a local override from a stale default, so the local value is kept.
+func threeWayMerge(base, local, source *ast.Assignment) mergeDecision {
+	if base == nil || source == nil {
+		return keepLocal
+	}
+
+	localChanged := local.Literal != base.Literal
+	sourceChanged := source.Literal != base.Literal
+
+	switch {
+	case !sourceChanged, local.Literal == source.Literal:
+		return keepLocal
+
+	case !localChanged:
+		return takeSource
+
+	default:
+		return conflict
+	}
+}
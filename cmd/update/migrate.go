@@ -0,0 +1,52 @@
+package update
+
+import (
+	"strings"
+
+	"github.com/jippi/dottie/pkg/ast"
+)
+
+const (
+	// renamedFromAnnotation on a SOURCE assignment names the KEY it was renamed from,
+	// so the local value of the old KEY is carried over to the new KEY
+	renamedFromAnnotation = "dottie/renamed-from"
+
+	// removedAnnotation on a SOURCE assignment marks the KEY as intentionally removed (a tombstone),
+	// so the local KEY is dropped instead of being added back to the file
+	removedAnnotation = "dottie/removed"
+)
+
+// migrations holds the KEY migrations declared in the SOURCE document
+type migrations struct {
+	renamed map[string]string // old KEY => new KEY
+	removed map[string]string // removed KEY => (optional) reason
+}
+
+// findMigrations collects the KEY migrations from the annotations in the [document],
+// and deletes the tombstone assignments from it
+func findMigrations(document *ast.Document) (*migrations, error) {
+	result := &migrations{
+		renamed: map[string]string{},
+		removed: map[string]string{},
+	}
+
+	for _, assignment := range document.AllAssignments() {
+		for _, oldName := range assignment.Annotation(renamedFromAnnotation) {
+			if oldName = strings.TrimSpace(oldName); len(oldName) > 0 {
+				result.renamed[oldName] = assignment.Name
+			}
+		}
+
+		if reasons := assignment.Annotation(removedAnnotation); len(reasons) > 0 {
+			result.removed[assignment.Name] = strings.TrimSpace(strings.Join(reasons, " "))
+		}
+	}
+
+	for name := range result.removed {
+		if _, err := document.Delete(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
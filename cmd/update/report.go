@@ -0,0 +1,139 @@
+package update
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jippi/dottie/pkg/tui"
+)
+
+// reportCategory is the outcome of [update] for a single KEY
+type reportCategory uint
+
+const (
+	reportUnchanged         reportCategory = iota // The KEY is in both FILE and SOURCE, and the SOURCE value was kept
+	reportUpdatedFromLocal                        // The KEY is in both FILE and SOURCE, and the FILE value was kept
+	reportUpdatedFromSource                       // The KEY was not changed locally, so the new SOURCE value was used
+	reportAddedFromSource                         // The KEY is new in SOURCE
+	reportLocalOnly                               // The KEY is in FILE, but not in SOURCE
+	reportDeprecated                              // The KEY was renamed or removed by the SOURCE
+)
+
+var reportCategories = []reportCategory{
+	reportUnchanged,
+	reportUpdatedFromLocal,
+	reportUpdatedFromSource,
+	reportAddedFromSource,
+	reportLocalOnly,
+	reportDeprecated,
+}
+
+func (c reportCategory) String() string {
+	switch c {
+	case reportUnchanged:
+		return "Unchanged"
+
+	case reportUpdatedFromLocal:
+		return "Updated from local"
+
+	case reportUpdatedFromSource:
+		return "Updated from source"
+
+	case reportAddedFromSource:
+		return "Added from source"
+
+	case reportLocalOnly:
+		return "Local only"
+
+	case reportDeprecated:
+		return "Deprecated"
+
+	default:
+		panic(fmt.Errorf("unexpected reportCategory value: %d", c))
+	}
+}
+
+// pruneMode is how [--prune-local-only] handles KEYs that are in FILE, but not in SOURCE
+type pruneMode uint
+
+const (
+	pruneNone    pruneMode = iota // Keep the KEY
+	pruneDisable                  // Keep the KEY, but disable it
+	pruneRemove                   // Drop the KEY
+)
+
+func pruneModeFromString(in string) (pruneMode, error) {
+	switch in {
+	case "":
+		return pruneNone, nil
+
+	case "disable":
+		return pruneDisable, nil
+
+	case "remove":
+		return pruneRemove, nil
+
+	default:
+		return 0, fmt.Errorf("invalid [--prune-local-only] value [ %s ], must be one of: disable, remove", in)
+	}
+}
+
+// report collects the outcome of [update] for every KEY
+type report struct {
+	seen map[string]bool
+	keys map[reportCategory][]string
+}
+
+func newReport() *report {
+	return &report{
+		seen: map[string]bool{},
+		keys: map[reportCategory][]string{},
+	}
+}
+
+// add records the outcome of the KEY [name], with an optional [note] explaining it.
+//
+// Only the first outcome of a KEY is recorded.
+func (r *report) add(category reportCategory, name, note string) {
+	if r.seen[name] {
+		return
+	}
+
+	r.seen[name] = true
+
+	if len(note) > 0 {
+		name += " (" + note + ")"
+	}
+
+	r.keys[category] = append(r.keys[category], name)
+}
+
+func (r *report) has(name string) bool {
+	return r.seen[name]
+}
+
+func (r *report) print(stdout tui.Writer) {
+	stdout.NoColor().Println("Summary")
+
+	for _, category := range reportCategories {
+		keys := r.keys[category]
+		if len(keys) == 0 {
+			continue
+		}
+
+		color := stdout.Success()
+
+		switch category {
+		case reportUnchanged:
+			color = stdout.NoColor()
+
+		case reportLocalOnly, reportDeprecated:
+			color = stdout.Warning()
+
+		case reportUpdatedFromLocal, reportUpdatedFromSource, reportAddedFromSource:
+		}
+
+		color.Print("  ", category.String(), " (", len(keys), "): ")
+		stdout.NoColor().Println(strings.Join(keys, ", "))
+	}
+}
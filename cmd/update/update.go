@@ -1,18 +1,21 @@
 package update
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/hashicorp/go-getter/v2"
 	exec_cmd "github.com/jippi/dottie/cmd/exec"
 	"github.com/jippi/dottie/pkg"
 	"github.com/jippi/dottie/pkg/ast"
 	"github.com/jippi/dottie/pkg/ast/upsert"
 	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/jippi/dottie/pkg/render"
+	"github.com/jippi/dottie/pkg/source"
 	"github.com/jippi/dottie/pkg/tui"
 	"github.com/jippi/dottie/pkg/validation"
 	"github.com/spf13/cobra"
@@ -27,7 +30,7 @@ func New() *cobra.Command {
 		RunE:    runE,
 	}
 
-	cmd.Flags().String("source", "", "URL or local file path to the upstream source file. This will take precedence over any [@dottie/source] annotation in the file")
+	cmd.Flags().StringArray("source", []string{}, "URL or local file path to the upstream source file, can be repeated to merge multiple sources in order. This will take precedence over any [@dottie/source] annotations in the file")
 	cmd.Flags().StringSlice("ignore-rule", []string{}, "Ignore this validation rule (e.g. 'dir')")
 	cmd.Flags().StringSlice("exclude-key-prefix", []string{}, "Ignore these KEY prefixes")
 
@@ -36,7 +39,25 @@ func New() *cobra.Command {
 	shared.BoolWithInverse(cmd, "backup", true, "Should the .env file be backed up before updating it?", "Skip backup of the env file before updating")
 	cmd.Flags().String("backup-file", "", "File path to write the backup to (by default it will write a '.env.dottie-backup' file in the same directory)")
 
+	shared.BoolWithInverse(cmd, "base", true, "Use (and record) a snapshot of SOURCE for a three-way merge", "Do not use or record a snapshot of SOURCE, FILE values always win")
+	cmd.Flags().String("base-file", "", "File path of the SOURCE snapshot (by default it will use a '.env.dottie-base' file in the same directory)")
+
+	shared.BoolWithInverse(cmd, "cache", true, "Cache fetched sources, so they can be used with [--offline]", "Do not cache fetched sources")
+	cmd.Flags().String("cache-dir", "", "Directory to cache fetched sources in (by default [$DOTTIE_CACHE_DIR], or a 'dottie/sources' directory in the user cache directory)")
+	cmd.Flags().Duration("cache-ttl", 0, "Use the cached copy of a source fetched within this duration (e.g. '1h') instead of fetching it again")
+	cmd.Flags().Bool("offline", false, "Never fetch sources, use the cached copies instead")
+
+	cmd.Flags().String("lock-file", "", "File path of the SOURCE lock file (by default it will use a '.env.dottie-sources.lock' file in the same directory)")
+	cmd.Flags().Bool("locked", false, "Error if SOURCE changed since it was locked, or if there is no lock file")
+	cmd.Flags().Bool("refresh-lock", false, "Lock the current SOURCE, even if it changed since it was locked")
+	cmd.MarkFlagsMutuallyExclusive("locked", "refresh-lock")
+
 	shared.BoolWithInverse(cmd, "error-on-missing-key", false, "Error if a KEY in FILE is missing from SOURCE", "Add KEY to FILE if missing from SOURCE")
+	shared.BoolWithInverse(cmd, "error-on-conflict", false, "Error if a KEY was changed in both FILE and SOURCE since the last update", "Keep the FILE value if a KEY was changed in both FILE and SOURCE since the last update")
+	cmd.Flags().Bool("interactive", false, "Ask how to resolve conflicts, missing keys and validation errors instead of aborting")
+	cmd.Flags().String("prune-local-only", "", "Prune KEYs in FILE that are missing from SOURCE, by disabling them, or removing them with '--prune-local-only=remove' (the value must be given after '=')")
+	cmd.Flags().Lookup("prune-local-only").NoOptDefVal = "disable"
+
 	shared.BoolWithInverse(cmd, "validate", true, "Validation errors will abort the update", "Validation errors will be printed but will not fail the update")
 	shared.BoolWithInverse(cmd, "save", true, "Save the document after processing", "Do not save the document after processing")
 	shared.BoolWithInverse(cmd, "exec", false, "Run exec annotations after updating", "Do not run exec annotations after updating")
@@ -47,6 +68,24 @@ func New() *cobra.Command {
 func runE(cmd *cobra.Command, args []string) error {
 	filename := cmd.Flag("file").Value.String()
 
+	unlock, err := pkg.Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	prune, err := pruneModeFromString(shared.StringFlag(cmd.Flags(), "prune-local-only"))
+	if err != nil {
+		return err
+	}
+
+	interactive := shared.BoolFlag(cmd.Flags(), "interactive")
+	if interactive {
+		if err := ensureInteractive(); err != nil {
+			return err
+		}
+	}
+
 	stdout, stderr := tui.WritersFromContext(cmd.Context())
 
 	noColor := stdout.NoColor()
@@ -70,72 +109,164 @@ func runE(cmd *cobra.Command, args []string) error {
 		oldDocument = ast.NewDocument()
 	}
 
-	source, _ := cmd.Flags().GetString("source")
-	if len(source) == 0 {
-		source, err = oldDocument.GetConfig("dottie/source")
-		if err != nil {
+	sources, _ := cmd.Flags().GetStringArray("source")
+	if len(sources) == 0 {
+		if _, err := oldDocument.GetConfig(source.Annotation); err != nil {
 			return err
 		}
 
-		success.Println("  Found source via [dottie/source] annotation in file", primary.Sprint(filename))
-	} else {
+		sources = oldDocument.GetConfigs(source.Annotation)
+
+		if len(sources) == 1 {
+			success.Println("  Found source via [dottie/source] annotation in file", primary.Sprint(filename))
+		} else {
+			success.Println("  Found", len(sources), "sources via [dottie/source] annotations in file", primary.Sprint(filename))
+		}
+	} else if len(sources) == 1 {
 		success.Println("  Found source via CLI flag")
+	} else {
+		success.Println("  Found", len(sources), "sources via CLI flag")
 	}
 
 	noColor.Println()
 
-	noColor.Println("Copying source from", primary.Sprint(source))
-
-	tmp, err := os.CreateTemp(os.TempDir(), ".dottie.source")
+	fetchCtx, err := withSourceCache(cmd)
 	if err != nil {
 		return err
 	}
 
-	// Get the pwd
-	pwd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("error getting working directory: %w", err)
-	}
+	layers := make([]*source.Layer, 0, len(sources))
+
+	for _, sourceURL := range sources {
+		noColor.Println("Copying source from", primary.Sprint(sourceURL))
 
-	// Grab source file
+		layer, err := source.FetchLayer(fetchCtx, sourceURL)
+		if err != nil {
+			return err
+		}
 
-	client := getter.Client{
-		DisableSymlinks: true,
-		Getters: []getter.Getter{
-			&getter.FileGetter{},
-			&getter.GitGetter{},
-			&getter.HttpGetter{
-				XTerraformGetDisabled: true,
-				Netrc:                 true,
-				DoNotCheckHeadFirst:   true,
-			},
-		},
+		layers = append(layers, layer)
+
+		if layer.Cached {
+			success.Println("  OK (from cache)")
+		} else {
+			success.Println("  OK")
+		}
+
+		success.Println()
 	}
 
-	opts := &getter.Request{
-		Pwd:     pwd,
-		GetMode: getter.ModeFile,
-		Dst:     tmp.Name(),
-		Src:     source,
+	// Compare the sources with the ones pinned in the lock file
+	lockFile := source.LockFilePath(filename)
+	if f := shared.StringFlag(cmd.Flags(), "lock-file"); len(f) > 0 {
+		lockFile = f
 	}
 
-	if _, err := client.Get(cmd.Context(), opts); err != nil {
+	newLock := source.NewLockFile(layers, time.Now().UTC())
+	writeLock := shared.BoolFlag(cmd.Flags(), "refresh-lock")
+
+	noColor.Println("Checking source lock file", primary.Sprint(lockFile))
+
+	oldLock, err := source.ReadLockFile(lockFile)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if shared.BoolFlag(cmd.Flags(), "locked") {
+			danger.Println("  ERROR lock file not found")
+
+			return fmt.Errorf("[--locked] was provided, but the lock file [ %s ] does not exist", lockFile)
+		}
+
+		stdout.Warning().Println("  Not found, it will be created")
+
+		writeLock = true
+
+	case err != nil:
 		return err
+
+	case !oldLock.Matches(newLock):
+		color := stdout.Warning()
+		if shared.BoolFlag(cmd.Flags(), "locked") {
+			color = danger
+		}
+
+		for _, change := range oldLock.Changes(newLock) {
+			color.Println("  " + change)
+		}
+
+		switch {
+		case shared.BoolFlag(cmd.Flags(), "locked"):
+			return fmt.Errorf("[--locked] was provided, but the sources do not match the lock file [ %s ]", lockFile)
+
+		case writeLock:
+			color.Println("  The lock file will be refreshed")
+
+		default:
+			color.Println("  Use [--refresh-lock] to lock the new sources")
+		}
+
+	default:
+		success.Println("  OK")
 	}
 
-	success.Println("  OK")
-	success.Println()
+	noColor.Println()
+
+	// Merge the layers into a single source document
+	newDocument, attribution, err := source.Merge(cmd.Context(), layers)
+	if err != nil {
+		return err
+	}
+
+	if len(layers) > 1 {
+		noColor.Println("Merging", len(layers), "sources")
+
+		for _, assignment := range newDocument.AllAssignments() {
+			noColor.Print("  [", assignment.Name, "]")
+			noColor.Print(" from ")
+			primary.Println(attribution[assignment.Name])
+		}
+
+		success.Println("  OK")
+		success.Println()
+	}
 
-	// Load the soon-to-be-merged file
-	noColor.Println("Loading and parsing source")
+	// Keep the merged source, so it can be saved as the base snapshot of the next update
+	mergedSource := render.NewFormatter().Statement(cmd.Context(), newDocument).String()
 
-	newDocument, err := pkg.Load(cmd.Context(), tmp.Name())
+	// Find renamed and removed keys, and drop the tombstones from the source
+	migrated, err := findMigrations(newDocument)
 	if err != nil {
 		return err
 	}
 
-	success.Println("  OK")
-	success.Println()
+	// Load the snapshot of the source from the previous update, used as base for a three-way merge
+	useBase := shared.BoolWithInverseValue(cmd.Flags(), "base")
+
+	baseFile := filename + ".dottie-base"
+	if f := shared.StringFlag(cmd.Flags(), "base-file"); len(f) > 0 {
+		baseFile = f
+	}
+
+	var baseDocument *ast.Document
+
+	if useBase {
+		noColor.Println("Loading base snapshot from", primary.Sprint(baseFile))
+
+		baseDocument, err = pkg.Load(cmd.Context(), baseFile)
+
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			stdout.Warning().Println("  Not found, values in", primary.Sprint(filename), "will take precedence over", primary.Sprint(strings.Join(sources, ", ")))
+
+		case err != nil:
+			return err
+
+		default:
+			success.Println("  OK")
+		}
+
+		noColor.Println()
+	}
 
 	// Take current assignments and set them in the new doc
 	noColor.Println("Updating source with key/value pairs from", primary.Sprint(filename))
@@ -144,6 +275,9 @@ func runE(cmd *cobra.Command, args []string) error {
 	sawError := false
 	lastWasError := false
 	counter := 0
+	conflicts := 0
+	outcome := newReport()
+	keptLocalOnly := 0
 
 	var selectors []ast.Selector
 
@@ -158,12 +292,160 @@ func runE(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, oldStatement := range oldDocument.AllAssignments(selectors...) {
+		if reason, ok := migrated.removed[oldStatement.Name]; ok {
+			counter++
+
+			outcome.add(reportDeprecated, oldStatement.Name, "removed")
+
+			stderr.Warning().Print("  [", oldStatement.Name, "]")
+			stderr.NoColor().Print(" was removed, since the source marked it as [@dottie/removed]")
+
+			if len(reason) > 0 {
+				stderr.NoColor().Print(": ", reason)
+			}
+
+			stderr.NoColor().Println()
+
+			continue
+		}
+
+		if newName, ok := migrated.renamed[oldStatement.Name]; ok && newDocument.Get(oldStatement.Name) == nil {
+			outcome.add(reportDeprecated, oldStatement.Name, "renamed to "+newName)
+
+			if oldDocument.Has(newName) {
+				stderr.Warning().Print("  [", oldStatement.Name, "]")
+				stderr.NoColor().Print(" was dropped, since it was renamed to ")
+				stderr.Warning().Print("[", newName, "]")
+				stderr.NoColor().Println(", which is already in the file")
+
+				continue
+			}
+
+			noColor.Print("  [", oldStatement.Name, "]")
+			noColor.Print(" was renamed to ")
+			primary.Print("[", newName, "]")
+			primary.Println()
+
+			renamed := *oldStatement
+			renamed.Name = newName
+			oldStatement = &renamed
+		}
+
+		// The assignment to merge into the SOURCE document
+		input := oldStatement
+
+		// How the KEY ended up in the SOURCE document, for the report
+		category := reportUpdatedFromLocal
+		note := ""
+
+		// Keep a copy of the SOURCE assignment, since merging into the SOURCE document changes it in-place
+		var sourceStatement *ast.Assignment
+
+		if existing := newDocument.Get(oldStatement.Name); existing != nil {
+			clone := *existing
+			sourceStatement = &clone
+		}
+
+		if sourceStatement == nil {
+			category = reportLocalOnly
+
+			switch prune {
+			case pruneRemove:
+				outcome.add(category, oldStatement.Name, "removed")
+
+				stderr.Warning().Print("  [", oldStatement.Name, "]")
+				stderr.NoColor().Println(" was removed, since it is missing from the source")
+
+				continue
+
+			case pruneDisable:
+				if input.Enabled {
+					note = "disabled"
+
+					disabled := *input
+					disabled.Enabled = false
+					input = &disabled
+				}
+
+			case pruneNone:
+			}
+		}
+
+		if baseDocument != nil {
+			switch threeWayMerge(baseDocument.Get(oldStatement.Name), oldStatement, sourceStatement) {
+			case takeSource:
+				counter++
+
+				if lastWasError {
+					danger.Println()
+				}
+
+				lastWasError = false
+
+				outcome.add(reportUpdatedFromSource, oldStatement.Name, "")
+
+				success.Print("  [", oldStatement.Name, "]")
+				noColor.Print(" was not changed locally, using the new source value ")
+				primary.Print("[", sourceStatement.Literal, "]")
+				primary.Println()
+
+				continue
+
+			case conflict:
+				conflicts++
+				counter++
+
+				color := stderr.Warning()
+				if shared.BoolWithInverseValue(cmd.Flags(), "error-on-conflict") {
+					color = stderr.Danger()
+				}
+
+				lastWasError = true
+				note = "conflict"
+
+				color.Print("  [", oldStatement.Name, "]")
+				stderr.NoColor().Print(" was changed both locally and in the source: ")
+				color.Println("base [", baseDocument.Get(oldStatement.Name).Literal, "] local [", oldStatement.Literal, "] source [", sourceStatement.Literal, "]")
+
+				if !interactive {
+					break
+				}
+
+				choice, resolved, err := resolveInteractively(cmd.Context(), newDocument, mergeConflict{
+					reason: "The key was changed both locally and in the source since the last update",
+					local:  oldStatement,
+					source: sourceStatement,
+				})
+				if err != nil {
+					return err
+				}
+
+				conflicts--
+				note = ""
+
+				if choice == resolveTakeSource {
+					outcome.add(reportUpdatedFromSource, oldStatement.Name, "")
+
+					success.Print("  [", oldStatement.Name, "]")
+					noColor.Print(" was resolved to the source value ")
+					primary.Print("[", sourceStatement.Literal, "]")
+					primary.Println()
+
+					continue
+				}
+
+				input = resolved
+
+			case keepLocal:
+			}
+		}
+
 		upserter, err := upsert.New(
 			newDocument,
 			upsert.EnableSetting(upsert.SkipIfSame),
 			upsert.EnableSetting(upsert.SkipIfEmpty),
 			upsert.EnableSetting(upsert.SkipIfSet),
-			upsert.EnableSettingIf(upsert.ErrorIfMissing, shared.BoolWithInverseValue(cmd.Flags(), "error-on-missing-key")),
+			upsert.EnableSettingIf(upsert.ErrorIfMissing, shared.BoolWithInverseValue(cmd.Flags(), "error-on-missing-key") && prune == pruneNone),
 			upsert.WithSkipValidationRule(shared.StringSliceFlag(cmd.Flags(), "ignore-rule")...),
 		)
 		if err != nil {
@@ -231,7 +513,45 @@ func runE(cmd *cobra.Command, args []string) error {
 
 		var skippedStatementWarning upsert.SkippedStatementError
 
-		changed, err := upserter.Upsert(cmd.Context(), oldStatement)
+		changed, err := upserter.Upsert(cmd.Context(), input)
+
+		// Let the user resolve missing keys and validation errors, and merge the resolved assignment instead
+		if interactive && err != nil && (!errors.As(err, &skippedStatementWarning) || skippedStatementWarning.IsError) {
+			reason := "The value failed validation"
+			if skippedStatementWarning.IsError {
+				reason = "The key is missing from the source"
+			}
+
+			choice, resolved, resolveErr := resolveInteractively(cmd.Context(), newDocument, mergeConflict{
+				reason: reason,
+				local:  oldStatement,
+				source: sourceStatement,
+			})
+			if resolveErr != nil {
+				return resolveErr
+			}
+
+			switch {
+			// The local value was already merged, but failed validation
+			case choice == resolveKeepLocal && !skippedStatementWarning.IsError:
+				err = nil
+
+			case choice == resolveTakeSource:
+				input = sourceStatement
+				category = reportUpdatedFromSource
+
+				changed, err = upserter.Upsert(cmd.Context(), input)
+
+			default:
+				input = resolved
+
+				upserter.ApplyOptions(upsert.DisableSetting(upsert.ErrorIfMissing))
+
+				changed, err = upserter.Upsert(cmd.Context(), input)
+			}
+
+			skippedStatementWarning = upsert.SkippedStatementError{}
+		}
 
 		switch {
 		case errors.As(err, &skippedStatementWarning):
@@ -251,12 +571,20 @@ func runE(cmd *cobra.Command, args []string) error {
 			stderr.NoColor().Print(" was skipped: ")
 			color.Println(skippedStatementWarning.Reason)
 
+			if skippedStatementWarning.IsError {
+				outcome.add(category, oldStatement.Name, "skipped")
+			} else {
+				outcome.add(reportUnchanged, oldStatement.Name, "")
+			}
+
 		case err != nil:
 			sawError = true
 			lastWasError = true
 
 			danger.Println(indent(validation.Explain(cmd.Context(), newDocument, err, changed, false, false), 2))
 
+			outcome.add(category, oldStatement.Name, "invalid")
+
 			counter++
 
 			continue
@@ -271,15 +599,62 @@ func runE(cmd *cobra.Command, args []string) error {
 
 			lastWasError = false
 
+			outcome.add(category, oldStatement.Name, note)
+
+			if category == reportLocalOnly && prune == pruneNone {
+				keptLocalOnly++
+			}
+
 			success.Print("  [", oldStatement.Name, "]")
+
+			if note == "disabled" {
+				noColor.Println(" was disabled, since it is missing from the source")
+
+				continue
+			}
+
 			noColor.Print(" was successfully set to ")
-			primary.Print("[", oldStatement.Literal, "]")
+			primary.Print("[", input.Literal, "]")
 			primary.Println()
 		}
 	}
 
 	stdout.NoColor().Println()
 
+	// Categorize the KEYs that were not merged from FILE
+	for _, assignment := range newDocument.AllAssignments() {
+		if outcome.has(assignment.Name) {
+			continue
+		}
+
+		if oldDocument.Has(assignment.Name) {
+			outcome.add(reportUnchanged, assignment.Name, "")
+		} else {
+			outcome.add(reportAddedFromSource, assignment.Name, "")
+		}
+	}
+
+	// KEYs that were not merged (e.g. disabled or excluded) and are missing from the SOURCE, are dropped from FILE
+	for _, assignment := range oldDocument.AllAssignments() {
+		if !outcome.has(assignment.Name) && newDocument.Get(assignment.Name) == nil {
+			outcome.add(reportLocalOnly, assignment.Name, "dropped")
+		}
+	}
+
+	outcome.print(stdout)
+
+	if keptLocalOnly > 0 {
+		stdout.Warning().Println("  Use [--prune-local-only] to disable or remove the local only keys")
+	}
+
+	stdout.NoColor().Println()
+
+	if conflicts > 0 && shared.BoolWithInverseValue(cmd.Flags(), "error-on-conflict") {
+		stdout.NoColor().Println()
+
+		return fmt.Errorf("%d keys were changed both locally and in the source, aborting", conflicts)
+	}
+
 	if sawError && shared.BoolWithInverseValue(cmd.Flags(), "validate") {
 		stdout.NoColor().Println()
 
@@ -330,6 +705,32 @@ func runE(cmd *cobra.Command, args []string) error {
 	success.Println("  OK")
 	success.Println()
 
+	if writeLock {
+		noColor.Println("Saving the source lock file to", primary.Sprint(lockFile))
+
+		if err := newLock.Write(lockFile); err != nil {
+			danger.Println("  ERROR", err.Error())
+
+			return err
+		}
+
+		success.Println("  OK")
+		success.Println()
+	}
+
+	if useBase {
+		noColor.Println("Saving the source snapshot to", primary.Sprint(baseFile))
+
+		if err := pkg.WriteFile(baseFile, []byte(mergedSource), 0o600); err != nil {
+			danger.Println("  ERROR", err.Error())
+
+			return err
+		}
+
+		success.Println("  OK")
+		success.Println()
+	}
+
 	success.Box("Update successfully completed")
 
 	if shared.BoolWithInverseValue(cmd.Flags(), "exec") {
@@ -348,6 +749,39 @@ func runE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// withSourceCache returns the command context with the source [source.Cache] configured by the flags
+func withSourceCache(cmd *cobra.Command) (context.Context, error) {
+	offline := shared.BoolFlag(cmd.Flags(), "offline")
+
+	if !shared.BoolWithInverseValue(cmd.Flags(), "cache") {
+		if offline {
+			return nil, errors.New("[--offline] can't be used with [--no-cache]")
+		}
+
+		return cmd.Context(), nil
+	}
+
+	dir := shared.StringFlag(cmd.Flags(), "cache-dir")
+	if len(dir) == 0 {
+		var err error
+
+		if dir, err = source.DefaultCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	ttl, err := cmd.Flags().GetDuration("cache-ttl")
+	if err != nil {
+		return nil, err
+	}
+
+	return source.ContextWithCache(cmd.Context(), &source.Cache{
+		Dir:     dir,
+		TTL:     ttl,
+		Offline: offline,
+	}), nil
+}
+
 func Copy(src, dst string) error {
 	srcF, err := os.Open(src)
 	if err != nil {
@@ -12,6 +12,21 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestMain(m *testing.M) {
+	// Keep the sources cached by the tests out of the user cache directory
+	dir, err := os.MkdirTemp("", "dottie-cache")
+	if err != nil {
+		panic(err)
+	}
+
+	os.Setenv("DOTTIE_CACHE_DIR", dir)
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestSetCommand(t *testing.T) {
 	t.Parallel()
 
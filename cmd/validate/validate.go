@@ -3,10 +3,14 @@ package validate
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/jippi/dottie/pkg"
 	"github.com/jippi/dottie/pkg/ast"
 	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/jippi/dottie/pkg/parser"
+	"github.com/jippi/dottie/pkg/template"
 	"github.com/jippi/dottie/pkg/tui"
 	"github.com/jippi/dottie/pkg/validation"
 	"github.com/spf13/cobra"
@@ -25,15 +29,21 @@ func New() *cobra.Command {
 	cmd.Flags().StringSlice("ignore-rule", []string{}, "Ignore this validation rule (e.g. 'dir')")
 
 	shared.BoolWithInverse(cmd, "fix", true, "Guide the user to fix supported validation errors", "Do not guide the user to fix supported validation errors")
+	cmd.Flags().Bool("strict", false, "Fail on references to unset variables without a default value")
 
 	return cmd
 }
 
 func runE(cmd *cobra.Command, args []string) error {
+	cmd.SetContext(template.ContextWithStrict(cmd.Context(), shared.BoolFlag(cmd.Flags(), "strict")))
+
 	filename := cmd.Flag("file").Value.String()
 
-	document, err := pkg.Load(cmd.Context(), filename)
-	if err != nil {
+	// Keep parsing past syntax errors, so all of them can be reported together with the validation errors
+	var syntaxErrors parser.Diagnostics
+
+	document, provenance, err := pkg.LoadLayered(cmd.Context(), filename, parser.WithErrorRecovery(true))
+	if err != nil && !errors.As(err, &syntaxErrors) {
 		return fmt.Errorf("failed to load file: %w", err)
 	}
 
@@ -67,15 +77,56 @@ func runE(cmd *cobra.Command, args []string) error {
 		return errs
 	}
 
+	danger := stderr.Danger()
+
+	if len(syntaxErrors) > 0 {
+		danger.Box(fmt.Sprintf("%d syntax errors found", len(syntaxErrors)))
+		danger.Println()
+
+		for _, syntaxError := range syntaxErrors {
+			stderr.NoColor().Println(syntaxError.Error())
+		}
+
+		stderr.NoColor().Println()
+	}
+
+	// Disabled lines next to an enabled one are a common way to keep alternative values around,
+	// so only KEYs with multiple enabled assignments fail validation
+	duplicates, alternatives := findDuplicates(document, excludedPrefixes)
+
+	if len(duplicates) > 0 {
+		danger.Box(fmt.Sprintf("%d duplicate keys found", len(duplicates)))
+		danger.Println()
+
+		printDuplicates(stderr, duplicates)
+
+		stderr.NoColor().Println()
+		stderr.Info().Println("Use [ dottie dedupe ] to keep only one assignment per KEY")
+		stderr.NoColor().Println()
+	}
+
+	if len(alternatives) > 0 {
+		stderr.Warning().Printfln("%d keys are also assigned on disabled lines", len(alternatives))
+
+		printDuplicates(stderr, alternatives)
+
+		stderr.NoColor().Println()
+	}
+
 	if len(validationErrors) == 0 {
+		if len(syntaxErrors) > 0 || len(duplicates) > 0 {
+			return errors.New("validation failed")
+		}
+
 		stderr.Success().Box("No validation errors found")
 
 		return nil
 	}
 
-	attemptFixOfValidationError := shared.BoolWithInverseValue(cmd.Flags(), "fix")
+	// Fixing a validation error saves the file, which would drop the lines with syntax errors,
+	// or write the merged keys of all the layered files into one of them
+	attemptFixOfValidationError := shared.BoolWithInverseValue(cmd.Flags(), "fix") && len(syntaxErrors) == 0 && !provenance.Layered()
 
-	danger := stderr.Danger()
 	danger.Box(fmt.Sprintf("%d validation errors found", len(validationErrors)))
 	danger.Println()
 
@@ -95,8 +146,8 @@ func runE(cmd *cobra.Command, args []string) error {
 	// Validate file again, in case some of the fixers from before fixed them
 	//
 
-	document, err = pkg.Load(cmd.Context(), filename)
-	if err != nil {
+	document, _, err = pkg.LoadLayered(cmd.Context(), filename, parser.WithErrorRecovery(true))
+	if err != nil && !errors.As(err, &syntaxErrors) {
 		return fmt.Errorf("failed to reload .env file: %w", err)
 	}
 
@@ -105,7 +156,7 @@ func runE(cmd *cobra.Command, args []string) error {
 		return errs
 	}
 
-	if len(newRes) == 0 {
+	if len(newRes) == 0 && len(syntaxErrors) == 0 && len(duplicates) == 0 {
 		stderr.Success().Println("All validation errors fixed")
 
 		return nil
@@ -122,3 +173,48 @@ func runE(cmd *cobra.Command, args []string) error {
 
 	return errors.New("validation failed")
 }
+
+// findDuplicates returns the KEYs assigned more than once, split into those with multiple
+// enabled assignments, and those where all but one of the assignments are disabled
+func findDuplicates(document *ast.Document, excludedPrefixes []string) ([]*ast.DuplicateKey, []*ast.DuplicateKey) {
+	var duplicates, alternatives []*ast.DuplicateKey
+
+	for _, duplicate := range document.Duplicates() {
+		if slices.ContainsFunc(excludedPrefixes, func(prefix string) bool { return strings.HasPrefix(duplicate.Name, prefix) }) {
+			continue
+		}
+
+		enabled := 0
+
+		for _, assignment := range duplicate.Assignments {
+			if assignment.Enabled {
+				enabled++
+			}
+		}
+
+		if enabled > 1 {
+			duplicates = append(duplicates, duplicate)
+
+			continue
+		}
+
+		alternatives = append(alternatives, duplicate)
+	}
+
+	return duplicates, alternatives
+}
+
+func printDuplicates(stderr tui.Writer, duplicates []*ast.DuplicateKey) {
+	for _, duplicate := range duplicates {
+		stderr.NoColor().Printfln("Key [ %s ] is assigned %d times", duplicate.Name, len(duplicate.Assignments))
+
+		for _, assignment := range duplicate.Assignments {
+			state := "enabled"
+			if !assignment.Enabled {
+				state = "disabled"
+			}
+
+			stderr.NoColor().Printfln("  * %s (%s)", assignment.Position, state)
+		}
+	}
+}
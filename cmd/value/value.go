@@ -2,10 +2,17 @@ package value
 
 import (
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/jippi/dottie/pkg"
 	"github.com/jippi/dottie/pkg/ast"
 	"github.com/jippi/dottie/pkg/cli/shared"
+	"github.com/jippi/dottie/pkg/template"
+	"github.com/jippi/dottie/pkg/token"
+	"github.com/jippi/dottie/pkg/tui"
 	"github.com/spf13/cobra"
 )
 
@@ -21,14 +28,19 @@ func New() *cobra.Command {
 
 	cmd.Flags().Bool("literal", false, "Show literal value instead of interpolated")
 	cmd.Flags().Bool("with-disabled", false, "Include disabled assignments")
+	cmd.Flags().Bool("explain", false, "Explain where each interpolated variable got its value from (the file or the process environment)")
+	cmd.MarkFlagsMutuallyExclusive("literal", "explain")
+	cmd.Flags().Bool("strict", false, "Fail on references to unset variables without a default value")
 
 	return cmd
 }
 
 func runE(cmd *cobra.Command, args []string) error {
+	cmd.SetContext(template.ContextWithStrict(cmd.Context(), shared.BoolFlag(cmd.Flags(), "strict")))
+
 	filename := cmd.Flag("file").Value.String()
 
-	document, err := pkg.Load(cmd.Context(), filename)
+	document, provenance, err := pkg.LoadLayered(cmd.Context(), filename)
 	if err != nil {
 		return err
 	}
@@ -61,7 +73,129 @@ func runE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if shared.BoolFlag(cmd.Flags(), "explain") {
+		return explain(cmd, document, provenance, assignment)
+	}
+
 	fmt.Fprint(cmd.OutOrStdout(), assignment.Interpolated)
 
 	return nil
 }
+
+// explain prints the interpolated value of the [assignment], which file it came from, and where each of its variables got its value from
+func explain(cmd *cobra.Command, document *ast.Document, provenance *pkg.Provenance, assignment *ast.Assignment) error {
+	stdout := tui.StdoutFromContext(cmd.Context())
+
+	noColor := stdout.NoColor()
+	primary := stdout.Primary()
+
+	policy, err := document.EnvPolicy(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	origin := "default"
+
+	switch {
+	case cmd.Flags().Changed("env-policy"):
+		origin = "from the [--env-policy] flag"
+
+	case len(document.GetConfigs(ast.EnvPolicyAnnotation)) > 0:
+		origin = "from the [@" + ast.EnvPolicyAnnotation + "] annotation"
+	}
+
+	noColor.Print("[", assignment.Name, "] = ")
+	primary.Print("[", assignment.Interpolated, "]")
+	noColor.Println()
+	noColor.Println()
+
+	if provenance.Layered() {
+		noColor.Print("Files: ")
+		primary.Println(strings.Join(provenance.Files, ", "))
+
+		files := provenance.Keys[assignment.Name]
+
+		noColor.Print("Value from: ")
+		primary.Print(provenance.Winner(assignment.Name))
+
+		if len(files) > 1 {
+			noColor.Print(" (overriding ", strings.Join(files[:len(files)-1], ", "), ")")
+		}
+
+		noColor.Println()
+		noColor.Println()
+	}
+
+	noColor.Print("Env policy: ")
+	primary.Print(policy.String())
+	noColor.Println(" (" + origin + ")")
+	noColor.Println()
+
+	noColor.Println("Variables")
+
+	// Single quoted values are never interpolated
+	if assignment.Quote.Is(token.SingleQuote.Rune()) {
+		noColor.Println("  (none, the value is single quoted)")
+
+		return nil
+	}
+
+	literal, err := assignment.Unquote(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	variables := template.ExtractVariables(cmd.Context(), literal)
+	if len(variables) == 0 {
+		noColor.Println("  (none)")
+
+		return nil
+	}
+
+	names := slices.Sorted(maps.Keys(variables))
+
+	for _, name := range names {
+		value, found := document.LookupVariable(policy, assignment, name)
+
+		// The values interpolation would have used if the policy preferred the other source
+		fileValue, fileOrigin := document.LookupVariable(ast.EnvPolicy{Mode: ast.FileOnly}, assignment, name)
+		envValue, inEnv := os.LookupEnv(name)
+
+		noColor.Print("  [", name, "] ")
+
+		switch found {
+		case ast.OriginEnvironment:
+			noColor.Print("= ")
+			primary.Print("[", value, "]")
+			noColor.Print(" from the environment")
+
+			if fileOrigin == ast.OriginDocument {
+				noColor.Print(", overriding the file value [", fileValue, "]")
+			}
+
+		case ast.OriginDocument:
+			noColor.Print("= ")
+			primary.Print("[", value, "]")
+			noColor.Print(" from the file")
+
+			if provenance.Layered() {
+				noColor.Print(" [", provenance.Winner(name), "]")
+			}
+
+			if inEnv {
+				noColor.Print(", the environment value [", envValue, "] was ignored")
+			}
+
+		case ast.OriginUnset:
+			stdout.Warning().Print("is not set")
+
+			if inEnv {
+				noColor.Print(", the environment value [", envValue, "] was ignored")
+			}
+		}
+
+		noColor.Println()
+	}
+
+	return nil
+}
@@ -1,11 +1,20 @@
 package value_test
 
 import (
+	"os"
 	"testing"
 
 	"github.com/jippi/dottie/pkg/test_helpers"
 )
 
+func TestMain(m *testing.M) {
+	// Variables in the process environment, used by the [--env-policy] tests
+	os.Setenv("DOTTIE_TEST_HOST", "env-host")
+	os.Setenv("DOTTIE_TEST_PORT", "5432")
+
+	os.Exit(m.Run())
+}
+
 func TestPrintCommand(t *testing.T) {
 	t.Parallel()
 
@@ -14,17 +14,36 @@ import (
 )
 
 type Assignment struct {
-	Complete     bool                         `json:"complete"`     // The key/value had no value/content after the "=" sign
-	Enabled      bool                         `json:"enabled"`      // The assignment was commented out (#KEY=VALUE)
-	Interpolated string                       `json:"interpolated"` // Value of the key (after interpolation)
-	Literal      string                       `json:"literal"`      // Value of the key (right hand side of the "=" sign)
-	Name         string                       `json:"key"`          // Name of the key (left hand side of the "=" sign)
-	Quote        token.Quote                  `json:"quote"`        // The style of quotes used for the assignment
-	Position     Position                     `json:"position"`     // Information about position of the assignment in the file
-	Comments     []*Comment                   `json:"comments"`     // Comments attached to the assignment (e.g. doc block before it)
-	Dependencies map[string]template.Variable `json:"dependencies"` // Assignments that this assignment depends on
-	Dependents   map[string]*Assignment       `json:"dependents"`   // Assignments dependents on this assignment
-	Group        *Group                       `json:"-"`            // The (optional) group this assignment belongs to
+	Complete      bool                         `json:"complete"`       // The key/value had no value/content after the "=" sign
+	Enabled       bool                         `json:"enabled"`        // The assignment was commented out (#KEY=VALUE)
+	Exported      bool                         `json:"exported"`       // The assignment was prefixed with "export" (export KEY=VALUE)
+	Interpolated  string                       `json:"interpolated"`   // Value of the key (after interpolation)
+	Literal       string                       `json:"literal"`        // Value of the key (right hand side of the "=" sign)
+	Name          string                       `json:"key"`            // Name of the key (left hand side of the "=" sign)
+	Quote         token.Quote                  `json:"quote"`          // The style of quotes used for the assignment
+	Position      Position                     `json:"position"`       // Information about position of the assignment in the file
+	NameSpan      Span                         `json:"name_span"`      // Location of the key (left hand side of the "=" sign)
+	AssignSpan    Span                         `json:"assign_span"`    // Location of the "=" sign
+	ValueSpan     Span                         `json:"value_span"`     // Location of the value (including quotes)
+	Comments      []*Comment                   `json:"comments"`       // Comments attached to the assignment (e.g. doc block before it)
+	InlineComment string                       `json:"inline_comment"` // Trailing comment on the assignment line (KEY=VALUE # comment)
+	Dependencies  map[string]template.Variable `json:"dependencies"`   // Assignments that this assignment depends on
+	Dependents    map[string]*Assignment       `json:"dependents"`     // Assignments dependents on this assignment
+	Group         *Group                       `json:"-"`              // The (optional) group this assignment belongs to
+
+	source *assignmentSource // The original line(s) from the source file, see [Assignment.Source]
+}
+
+// assignmentSource is the original text of an assignment, along with a snapshot of
+// the fields that affect how it's rendered, so changes can be detected.
+type assignmentSource struct {
+	text          string
+	name          string
+	literal       string
+	quote         token.Quote
+	enabled       bool
+	exported      bool
+	inlineComment string
 }
 
 func (a *Assignment) statementNode() {}
@@ -37,6 +56,29 @@ func (a *Assignment) Initialize(ctx context.Context) {
 	}
 }
 
+// Source returns the assignment exactly as it was in the source file.
+//
+// The boolean is false if the original text is unknown, or the assignment has been changed since.
+func (a *Assignment) Source() (string, bool) {
+	if a.source == nil || *a.source != a.newSource(a.source.text) {
+		return "", false
+	}
+
+	return a.source.text, true
+}
+
+func (a *Assignment) newSource(text string) assignmentSource {
+	return assignmentSource{
+		text:          text,
+		name:          a.Name,
+		literal:       a.Literal,
+		quote:         a.Quote,
+		enabled:       a.Enabled,
+		exported:      a.Exported,
+		inlineComment: a.InlineComment,
+	}
+}
+
 func (a *Assignment) Is(other Statement) bool {
 	if a == nil || other == nil {
 		return false
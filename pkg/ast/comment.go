@@ -35,6 +35,20 @@ func NewComment(value string) *Comment {
 	}
 }
 
+// NewInlineComment returns the trailing comment literal for an assignment line,
+// or an empty string if [value] is empty.
+//
+// A leading "#" in [value] is dropped, so "# note" and "note" give the same comment.
+func NewInlineComment(value string) string {
+	value = strings.TrimLeft(strings.TrimPrefix(value, "#"), " ")
+
+	if len(value) == 0 {
+		return ""
+	}
+
+	return "# " + value
+}
+
 func (c *Comment) Is(other Statement) bool {
 	if c == nil || other == nil {
 		return false
@@ -0,0 +1,144 @@
+// Package diff compares two .env documents key by key.
+package diff
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jippi/dottie/pkg/ast"
+)
+
+// Status describes how a KEY differs between the two documents
+type Status string
+
+const (
+	Added   Status = "added"   // The KEY only exists in the new document
+	Removed Status = "removed" // The KEY only exists in the old document
+	Changed Status = "changed" // The KEY exists in both documents, but with [Change]s
+)
+
+// Field is the part of an assignment that a [Change] is about
+type Field string
+
+const (
+	FieldValue         Field = "value"          // The value, including its quotes
+	FieldEnabled       Field = "enabled"        // The assignment was enabled or disabled
+	FieldGroup         Field = "group"          // The assignment moved to another group
+	FieldComments      Field = "comments"       // The comments above the assignment (except annotations)
+	FieldAnnotations   Field = "annotations"    // The @dottie annotations above the assignment
+	FieldInlineComment Field = "inline_comment" // The trailing comment on the assignment line
+)
+
+// Change is a single difference between two assignments of the same KEY
+type Change struct {
+	Field Field  `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// KeyDiff is the difference of a single KEY between the two documents
+type KeyDiff struct {
+	Key     string          `json:"key"`
+	Status  Status          `json:"status"`
+	Changes []Change        `json:"changes,omitempty"`
+	Old     *ast.Assignment `json:"-"` // The assignment in the old document (nil if [Added])
+	New     *ast.Assignment `json:"-"` // The assignment in the new document (nil if [Removed])
+}
+
+// Result is the difference between two documents
+type Result struct {
+	Keys []*KeyDiff `json:"keys"`
+}
+
+// Equal reports if the documents have no differences
+func (r *Result) Equal() bool {
+	return len(r.Keys) == 0
+}
+
+// Documents compares the [oldDoc] and [newDoc] documents.
+//
+// Added and changed keys are returned in the order of [newDoc], followed by the removed keys in the order of [oldDoc].
+// If a KEY is assigned more than once, only the first assignment is compared (see [ast.Document.Get]).
+func Documents(oldDoc, newDoc *ast.Document) *Result {
+	result := &Result{Keys: []*KeyDiff{}}
+	seen := map[string]struct{}{}
+
+	for _, assignment := range newDoc.AllAssignments() {
+		if _, ok := seen[assignment.Name]; ok {
+			continue
+		}
+
+		seen[assignment.Name] = struct{}{}
+
+		newAssignment := newDoc.Get(assignment.Name)
+
+		oldAssignment := oldDoc.Get(assignment.Name)
+		if oldAssignment == nil {
+			result.Keys = append(result.Keys, &KeyDiff{Key: assignment.Name, Status: Added, New: newAssignment})
+
+			continue
+		}
+
+		if changes := Assignments(oldAssignment, newAssignment); len(changes) > 0 {
+			result.Keys = append(result.Keys, &KeyDiff{Key: assignment.Name, Status: Changed, Changes: changes, Old: oldAssignment, New: newAssignment})
+		}
+	}
+
+	for _, assignment := range oldDoc.AllAssignments() {
+		if _, ok := seen[assignment.Name]; ok {
+			continue
+		}
+
+		seen[assignment.Name] = struct{}{}
+
+		result.Keys = append(result.Keys, &KeyDiff{Key: assignment.Name, Status: Removed, Old: oldDoc.Get(assignment.Name)})
+	}
+
+	return result
+}
+
+// Assignments returns the changes between two assignments of the same KEY
+func Assignments(oldAssignment, newAssignment *ast.Assignment) []Change {
+	var changes []Change
+
+	compare := func(field Field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, Change{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+
+	compare(FieldValue, Value(oldAssignment), Value(newAssignment))
+	compare(FieldEnabled, strconv.FormatBool(oldAssignment.Enabled), strconv.FormatBool(newAssignment.Enabled))
+	compare(FieldGroup, groupName(oldAssignment), groupName(newAssignment))
+	compare(FieldComments, comments(oldAssignment, false), comments(newAssignment, false))
+	compare(FieldAnnotations, comments(oldAssignment, true), comments(newAssignment, true))
+	compare(FieldInlineComment, oldAssignment.InlineComment, newAssignment.InlineComment)
+
+	return changes
+}
+
+// Value returns the value of the assignment as written in the file, including its quotes
+func Value(assignment *ast.Assignment) string {
+	return assignment.Quote.String() + assignment.Literal + assignment.Quote.String()
+}
+
+func groupName(assignment *ast.Assignment) string {
+	if assignment.Group == nil {
+		return ""
+	}
+
+	return assignment.Group.String()
+}
+
+// comments returns either the annotations or the regular comments of the assignment, one per line
+func comments(assignment *ast.Assignment, annotations bool) string {
+	var lines []string
+
+	for _, comment := range assignment.Comments {
+		if (comment.Annotation != nil) == annotations {
+			lines = append(lines, comment.Value)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,83 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/ast/diff"
+	"github.com/stretchr/testify/require"
+)
+
+func parseDocument(t *testing.T, lines ...string) *ast.Document {
+	t.Helper()
+
+	doc, err := pkg.Parse(t.Context(), strings.NewReader(strings.Join(lines, "\n")), "test.env")
+	require.NoError(t, err)
+
+	return doc
+}
+
+func TestDocuments(t *testing.T) {
+	t.Parallel()
+
+	oldDoc := parseDocument(t,
+		"A=1",
+		"# @dottie/validate number",
+		"B=2",
+		"#C=3",
+		"REMOVED=x",
+	)
+
+	newDoc := parseDocument(t,
+		"ADDED=y",
+		"A='1'",
+		"# about B",
+		"# @dottie/validate number,gte=1",
+		"B=2",
+		"C=3",
+	)
+
+	result := diff.Documents(oldDoc, newDoc)
+	require.False(t, result.Equal())
+
+	require.Equal(t, []*diff.KeyDiff{
+		{Key: "ADDED", Status: diff.Added, New: newDoc.Get("ADDED")},
+		{
+			Key:     "A",
+			Status:  diff.Changed,
+			Changes: []diff.Change{{Field: diff.FieldValue, Old: `1`, New: `'1'`}},
+			Old:     oldDoc.Get("A"),
+			New:     newDoc.Get("A"),
+		},
+		{
+			Key:    "B",
+			Status: diff.Changed,
+			Changes: []diff.Change{
+				{Field: diff.FieldComments, Old: "", New: "# about B"},
+				{Field: diff.FieldAnnotations, Old: "# @dottie/validate number", New: "# @dottie/validate number,gte=1"},
+			},
+			Old: oldDoc.Get("B"),
+			New: newDoc.Get("B"),
+		},
+		{
+			Key:     "C",
+			Status:  diff.Changed,
+			Changes: []diff.Change{{Field: diff.FieldEnabled, Old: "false", New: "true"}},
+			Old:     oldDoc.Get("C"),
+			New:     newDoc.Get("C"),
+		},
+		{Key: "REMOVED", Status: diff.Removed, Old: oldDoc.Get("REMOVED")},
+	}, result.Keys)
+}
+
+func TestDocumentsEqual(t *testing.T) {
+	t.Parallel()
+
+	doc := parseDocument(t, "A=1", "B=2")
+
+	result := diff.Documents(doc, parseDocument(t, "A=1", "B=2"))
+	require.True(t, result.Equal())
+	require.Empty(t, result.Keys)
+}
@@ -6,12 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"os"
 	"reflect"
 	"slices"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/jippi/dottie/pkg/scanner"
 	"github.com/jippi/dottie/pkg/template"
 	"github.com/jippi/dottie/pkg/token"
 	slogctx "github.com/veqryn/slog-context"
@@ -25,6 +25,16 @@ type Document struct {
 	Annotations []*Comment  `json:"-"`          // Global annotations for configuration of dottie
 
 	interpolateErrors error
+
+	// interpolated tracks the assignments interpolated by [Document.InterpolateAll] and [Document.Validate],
+	// so dependencies shared by many assignments are only interpolated once
+	interpolated map[*Assignment]struct{}
+
+	// index maps KEY names to their (first) assignment, see [Document.Get]
+	index map[string]*Assignment
+
+	// duplicates are the KEYs assigned more than once, see [Document.Duplicates]
+	duplicates []*DuplicateKey
 }
 
 func NewDocument() *Document {
@@ -54,6 +64,31 @@ func (d *Document) BelongsToGroup(name string) bool {
 func (d *Document) statementNode() {
 }
 
+// SetSource records the original text of all assignments and group headers from
+// the [source] the document was parsed from, so they can be written back unchanged.
+func (d *Document) SetSource(source string) {
+	slice := func(offset, endOffset int) (string, bool) {
+		if endOffset <= offset || endOffset > len(source) {
+			return "", false
+		}
+
+		return source[offset:endOffset], true
+	}
+
+	for _, assignment := range d.AllAssignments() {
+		if text, ok := slice(assignment.Position.Offset, assignment.Position.EndOffset); ok {
+			snapshot := assignment.newSource(text)
+			assignment.source = &snapshot
+		}
+	}
+
+	for _, group := range d.Groups {
+		if text, ok := slice(group.HeaderSpan.Offset, group.HeaderSpan.EndOffset); ok {
+			group.source = &groupSource{text: text, name: group.Name}
+		}
+	}
+}
+
 func (d *Document) AllAssignments(selectors ...Selector) []*Assignment {
 	var assignments []*Assignment
 
@@ -105,14 +140,17 @@ func (d *Document) HasGroup(name string) bool {
 	return d.GetGroup(name) != nil
 }
 
+// Get returns the first assignment (enabled or not) named [name], or nil if there is none.
+//
+// Lookups use an index of the KEY names that is built on first use. The [Document] methods keep
+// it up-to-date, but code changing [Document.Statements] or [Group.Statements] directly must call
+// [Document.ReindexStatements] (or [Document.Initialize]) afterwards.
 func (d *Document) Get(name string) *Assignment {
-	for _, assign := range d.AllAssignments() {
-		if assign.Name == name {
-			return assign
-		}
+	if d.index == nil {
+		d.ReindexStatements()
 	}
 
-	return nil
+	return d.index[name]
 }
 
 func (d *Document) Has(name string) bool {
@@ -120,8 +158,11 @@ func (d *Document) Has(name string) bool {
 }
 
 func (doc *Document) InterpolateAll(ctx context.Context) error {
+	doc.interpolated = map[*Assignment]struct{}{}
+
 	defer func() {
 		doc.interpolateErrors = nil
+		doc.interpolated = nil
 	}()
 
 	for _, assignment := range doc.AllAssignments() {
@@ -172,6 +213,14 @@ func (doc *Document) doInterpolation(ctx context.Context, target *Assignment, in
 		return
 	}
 
+	if doc.interpolated != nil {
+		if _, ok := doc.interpolated[target]; ok {
+			return
+		}
+
+		doc.interpolated[target] = struct{}{}
+	}
+
 	target.Initialize(ctx)
 
 	// Interpolate dependencies of the assignment before the assignment itself
@@ -194,6 +243,15 @@ func (doc *Document) doInterpolation(ctx context.Context, target *Assignment, in
 		doc.doInterpolation(ctx, ref, false, interpolationStack) // when doing recursive interpolation, do not include disabled key/value pairs
 	}
 
+	// Executed commands can read the variables they use from their environment, so those must be interpolated first too
+	if template.CommandSubstitutionFromContext(ctx) != nil {
+		for _, name := range template.CommandVariables(target.Literal) {
+			if ref := doc.Get(name); ref != nil && ref.Enabled && ref.Position.Index < target.Position.Index {
+				doc.doInterpolation(ctx, ref, false, interpolationStack)
+			}
+		}
+	}
+
 	// If the assignment is wrapped in single quotes, no interpolation should happen
 	if target.Quote.Is(token.SingleQuote.Rune()) {
 		target.Interpolated = target.Literal
@@ -217,7 +275,7 @@ func (doc *Document) doInterpolation(ctx context.Context, target *Assignment, in
 		return
 	}
 
-	value, err := template.Substitute(ctx, unquotedLiteral, doc.InterpolationMapper(target), doc.AccessibleVariables(target))
+	value, err := template.Substitute(ctx, unquotedLiteral, doc.InterpolationMapper(ctx, target), doc.AccessibleVariables(target))
 	if err != nil {
 		err = fmt.Errorf("interpolation error for [%s] (%s): %w", target.Name, target.Position, err)
 	}
@@ -245,7 +303,14 @@ func (doc *Document) AccessibleVariables(target *Assignment) func() map[string]s
 	}
 }
 
-func (doc *Document) InterpolationMapper(target *Assignment) func(input string) (string, bool) {
+// InterpolationMapper returns the [template.Resolver] used to interpolate the [target] assignment,
+// which resolves variables from the document and the process environment according to the [Document.EnvPolicy]
+func (doc *Document) InterpolationMapper(ctx context.Context, target *Assignment) func(input string) (string, bool) {
+	policy, err := doc.EnvPolicy(ctx)
+	if err != nil {
+		doc.interpolateErrors = multierr.Append(doc.interpolateErrors, ContextualError(target, err))
+	}
+
 	return func(input string) (string, bool) {
 		if input == target.Name {
 			doc.interpolateErrors = multierr.Append(doc.interpolateErrors, ContextualError(target, fmt.Errorf("key [%s] must not reference itself", target.Name)))
@@ -253,29 +318,9 @@ func (doc *Document) InterpolationMapper(target *Assignment) func(input string)
 			return "", false
 		}
 
-		// Lookup in process environment
-		if val, ok := os.LookupEnv(input); ok {
-			return val, ok
-		}
-
-		// Search the currently available assignments in the document
-		assignment := doc.Get(input)
-		if assignment == nil {
-			return "", false
-		}
-
-		// If the assignment we found is on a index (sorted) *after* the target
-		// assignment, don't count it as found, since all normal shell interpolation
-		// are handled in order (e.g. line 5 can't use a variable from line 10)
-		if assignment.Position.Index >= target.Position.Index {
-			return "", false
-		}
+		value, origin := doc.LookupVariable(policy, target, input)
 
-		if !assignment.Enabled {
-			return "", false
-		}
-
-		return assignment.Interpolated, true
+		return value, origin != OriginUnset
 	}
 }
 
@@ -313,6 +358,19 @@ func (d *Document) GetConfig(name string) (string, error) {
 	return "", fmt.Errorf("could not find config key: [%s]", name)
 }
 
+// GetConfigs returns the values of every document annotation named [name], in the order they appear in the file
+func (d *Document) GetConfigs(name string) []string {
+	var values []string
+
+	for _, comment := range d.Annotations {
+		if comment.Annotation != nil && comment.Annotation.Key == name {
+			values = append(values, comment.Annotation.Value)
+		}
+	}
+
+	return values
+}
+
 func (d *Document) Assignments() []*Assignment {
 	var assignments []*Assignment
 
@@ -325,10 +383,41 @@ func (d *Document) Assignments() []*Assignment {
 	return assignments
 }
 
+// ReindexStatements refreshes the position index of all assignments and the KEY name index used by [Document.Get].
 func (d *Document) ReindexStatements() {
-	for i, stmt := range d.AllAssignments() {
+	assignments := d.AllAssignments()
+
+	d.index = make(map[string]*Assignment, len(assignments))
+	d.duplicates = nil
+
+	duplicates := map[string]*DuplicateKey{}
+
+	for i, stmt := range assignments {
 		stmt.Position.Index = i
+
+		// Only the first assignment of a KEY is indexed, the same one a linear search would find
+		first, ok := d.index[stmt.Name]
+		if !ok {
+			d.index[stmt.Name] = stmt
+
+			continue
+		}
+
+		duplicate, ok := duplicates[stmt.Name]
+		if !ok {
+			duplicate = &DuplicateKey{Name: stmt.Name, Assignments: []*Assignment{first}}
+			duplicates[stmt.Name] = duplicate
+
+			d.duplicates = append(d.duplicates, duplicate)
+		}
+
+		duplicate.Assignments = append(duplicate.Assignments, stmt)
 	}
+
+	// Order by the first assignment, rather than where the KEY was seen the second time
+	slices.SortFunc(d.duplicates, func(a, b *DuplicateKey) int {
+		return a.Assignments[0].Position.Index - b.Assignments[0].Position.Index
+	})
 }
 
 func (d *Document) GetAssignmentIndex(name string) (int, *Assignment) {
@@ -342,6 +431,8 @@ func (d *Document) GetAssignmentIndex(name string) (int, *Assignment) {
 }
 
 func (document *Document) Initialize(ctx context.Context) {
+	document.ReindexStatements()
+
 	allAssignments := document.AllAssignments()
 
 	for _, assignment := range allAssignments {
@@ -361,8 +452,6 @@ func (document *Document) Initialize(ctx context.Context) {
 			}
 		}
 	}
-
-	document.ReindexStatements()
 }
 
 func (document *Document) Replace(assignment *Assignment) error {
@@ -380,6 +469,7 @@ func (document *Document) Replace(assignment *Assignment) error {
 
 			if val.Name == assignment.Name {
 				existing.Group.Statements[idx] = assignment
+				document.index[assignment.Name] = assignment
 
 				return nil
 			}
@@ -394,6 +484,7 @@ func (document *Document) Replace(assignment *Assignment) error {
 
 		if val.Name == assignment.Name {
 			document.Statements[idx] = assignment
+			document.index[assignment.Name] = assignment
 
 			return nil
 		}
@@ -402,6 +493,109 @@ func (document *Document) Replace(assignment *Assignment) error {
 	return fmt.Errorf("could not find+replace KEY named [%s] in document", assignment.Name)
 }
 
+// Delete removes the KEY (and the comments attached to it) from the document,
+// returning the removed [Assignment].
+//
+// The caller is responsible for calling [Document.Initialize] afterwards to
+// refresh dependency information.
+func (document *Document) Delete(name string) (*Assignment, error) {
+	existing := document.Get(name)
+	if existing == nil {
+		return nil, fmt.Errorf("no KEY named [%s] exists in the document", name)
+	}
+
+	document.remove(existing)
+
+	// Another (e.g. disabled) assignment with the same name might be the one to find now
+	document.ReindexStatements()
+
+	return existing, nil
+}
+
+// remove removes the [assignment] (and the annotations attached to it) from the document
+// without reindexing it.
+func (document *Document) remove(assignment *Assignment) {
+	if assignment.Group != nil {
+		assignment.Group.Statements = slices.DeleteFunc(assignment.Group.Statements, func(stmt Statement) bool {
+			return stmt == assignment
+		})
+	} else {
+		document.Statements = slices.DeleteFunc(document.Statements, func(stmt Statement) bool {
+			return stmt == assignment
+		})
+	}
+
+	// Annotations attached to the assignment are also tracked on the document, so drop those as well
+	document.Annotations = slices.DeleteFunc(document.Annotations, func(comment *Comment) bool {
+		return slices.Contains(assignment.Comments, comment)
+	})
+}
+
+// Rename changes the name of the KEY [oldName] to [newName] while keeping its comments,
+// annotations and placement, and rewrites every reference to it in its dependents.
+//
+// The renamed [Assignment] is returned first, followed by the dependents that had their literal rewritten.
+func (document *Document) Rename(ctx context.Context, oldName, newName string) ([]*Assignment, error) {
+	if !scanner.IsValidKey(newName) {
+		return nil, fmt.Errorf("the KEY [%s] is not a valid KEY name", newName)
+	}
+
+	existing := document.Get(oldName)
+	if existing == nil {
+		return nil, fmt.Errorf("no KEY named [%s] exists in the document", oldName)
+	}
+
+	if document.Has(newName) {
+		return nil, fmt.Errorf("a KEY named [%s] already exists in the document", newName)
+	}
+
+	existing.Name = newName
+	changed := []*Assignment{existing}
+
+	// Check every assignment rather than only the [Assignment.Dependents], since those
+	// don't include nested references such as "${OTHER:-${OLD}}"
+	for _, dependent := range document.AllAssignments() {
+		// Single quoted values are never interpolated, so they do not contain any references
+		if dependent.Quote.Is(token.SingleQuote.Rune()) {
+			continue
+		}
+
+		literal, ok := template.RenameVariable(ctx, dependent.Literal, oldName, newName)
+		if !ok {
+			continue
+		}
+
+		dependent.Literal = literal
+		dependent.Interpolated = literal
+
+		if dependent != existing {
+			changed = append(changed, dependent)
+		}
+	}
+
+	document.Initialize(ctx)
+
+	return changed, nil
+}
+
+// RemoveEmptyGroups removes all groups that no longer contain any assignments,
+// returning the groups that was removed.
+func (document *Document) RemoveEmptyGroups() []*Group {
+	var removed []*Group
+
+	document.Groups = slices.DeleteFunc(document.Groups, func(group *Group) bool {
+		if len(group.Assignments()) > 0 {
+			return false
+		}
+
+		removed = append(removed, group)
+
+		return true
+	})
+
+	return removed
+}
+
 func (document *Document) Validate(ctx context.Context, selectors []Selector, ignoreErrors []string) ([]*ValidationError, error) {
 	var (
 		errors error
@@ -415,6 +609,12 @@ func (document *Document) Validate(ctx context.Context, selectors []Selector, ig
 		fieldOrder = []string{}
 	)
 
+	document.interpolated = map[*Assignment]struct{}{}
+
+	defer func() {
+		document.interpolated = nil
+	}()
+
 NEXT:
 	for _, assignment := range document.AllAssignments() {
 		for _, selector := range selectors {
@@ -0,0 +1,88 @@
+package ast_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/scanner"
+)
+
+// benchmarkSizes are the number of keys in the benchmarked documents; the "ns/key"
+// metric should stay roughly the same between them, since all operations are linear.
+var benchmarkSizes = []int{1_000, 10_000}
+
+// benchmarkDocument returns a document with [size] keys, each referencing the one before it
+func benchmarkDocument(b *testing.B, size int) *ast.Document {
+	b.Helper()
+
+	var builder strings.Builder
+
+	builder.WriteString("# @dottie/validate required\nKEY_0=value\n")
+
+	for i := 1; i < size; i++ {
+		fmt.Fprintf(&builder, "# @dottie/validate required\nKEY_%d=\"${KEY_%d}\"\n", i, i-1)
+	}
+
+	ctx := scanner.ContextWithLimits(b.Context(), scanner.Unlimited)
+
+	doc, err := pkg.Parse(ctx, strings.NewReader(builder.String()), "benchmark.env")
+	if err != nil {
+		b.Fatalf("failed to parse document: %v", err)
+	}
+
+	return doc
+}
+
+func runDocumentBenchmark(b *testing.B, operation func(b *testing.B, doc *ast.Document, size int)) {
+	b.Helper()
+
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("keys=%d", size), func(b *testing.B) {
+			doc := benchmarkDocument(b, size)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for b.Loop() {
+				operation(b, doc, size)
+			}
+
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*size), "ns/key")
+		})
+	}
+}
+
+func BenchmarkDocument_Get(b *testing.B) {
+	runDocumentBenchmark(b, func(b *testing.B, doc *ast.Document, size int) {
+		for i := range size {
+			if doc.Get(fmt.Sprintf("KEY_%d", i)) == nil {
+				b.Fatalf("expected KEY_%d to exist", i)
+			}
+		}
+	})
+}
+
+func BenchmarkDocument_Initialize(b *testing.B) {
+	runDocumentBenchmark(b, func(b *testing.B, doc *ast.Document, _ int) {
+		doc.Initialize(b.Context())
+	})
+}
+
+func BenchmarkDocument_InterpolateAll(b *testing.B) {
+	runDocumentBenchmark(b, func(b *testing.B, doc *ast.Document, _ int) {
+		if err := doc.InterpolateAll(b.Context()); err != nil {
+			b.Fatalf("failed to interpolate document: %v", err)
+		}
+	})
+}
+
+func BenchmarkDocument_Validate(b *testing.B) {
+	runDocumentBenchmark(b, func(b *testing.B, doc *ast.Document, _ int) {
+		if _, err := doc.Validate(b.Context(), nil, nil); err != nil {
+			b.Fatalf("failed to validate document: %v", err)
+		}
+	})
+}
@@ -133,7 +133,7 @@ func TestInterpolationMapperDoesNotResolveDisabledAssignments(t *testing.T) {
 
 	doc.Initialize(context.Background())
 
-	_, ok := doc.InterpolationMapper(target)("SECRET")
+	_, ok := doc.InterpolationMapper(context.Background(), target)("SECRET")
 	if ok {
 		t.Fatal("expected disabled assignment to be inaccessible via interpolation mapper")
 	}
@@ -0,0 +1,274 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/ast"
+)
+
+func parseDocument(t *testing.T, input string) *ast.Document {
+	t.Helper()
+
+	doc, err := pkg.Parse(t.Context(), strings.NewReader(input), "test.env")
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+
+	return doc
+}
+
+func TestDocumentDelete(t *testing.T) {
+	t.Parallel()
+
+	doc := parseDocument(t, strings.Join([]string{
+		"A=1",
+		"",
+		"###",
+		"# group",
+		"###",
+		"",
+		"# @dottie/validate number",
+		"B=2",
+		"C=3",
+	}, "\n"))
+
+	removed, err := doc.Delete("B")
+	if err != nil {
+		t.Fatalf("expected B to be deleted, got %v", err)
+	}
+
+	if removed.Name != "B" {
+		t.Fatalf("expected removed assignment to be B, got %q", removed.Name)
+	}
+
+	if doc.Has("B") {
+		t.Fatal("expected B to be gone from the document")
+	}
+
+	if len(doc.Annotations) != 0 {
+		t.Fatalf("expected annotations attached to B to be removed, got %d", len(doc.Annotations))
+	}
+
+	if _, err := doc.Delete("B"); err == nil {
+		t.Fatal("expected error when deleting a missing KEY")
+	}
+
+	if _, err := doc.Delete("A"); err != nil {
+		t.Fatalf("expected A to be deleted from the root, got %v", err)
+	}
+
+	if got := len(doc.AllAssignments()); got != 1 {
+		t.Fatalf("expected 1 assignment left, got %d", got)
+	}
+
+	if removed := doc.RemoveEmptyGroups(); len(removed) != 0 {
+		t.Fatalf("expected no groups to be removed, got %d", len(removed))
+	}
+
+	if _, err := doc.Delete("C"); err != nil {
+		t.Fatalf("expected C to be deleted, got %v", err)
+	}
+
+	if removed := doc.RemoveEmptyGroups(); len(removed) != 1 {
+		t.Fatalf("expected the empty group to be removed, got %d", len(removed))
+	}
+
+	if len(doc.Groups) != 0 {
+		t.Fatalf("expected no groups left, got %d", len(doc.Groups))
+	}
+}
+
+func TestDocumentRename(t *testing.T) {
+	t.Parallel()
+
+	doc := parseDocument(t, strings.Join([]string{
+		"# docs for A",
+		"A=1",
+		"B=${A:-2}",
+		"C='${A}'",
+		"D=$A$A",
+	}, "\n"))
+
+	changed, err := doc.Rename(t.Context(), "A", "Z")
+	if err != nil {
+		t.Fatalf("expected rename to succeed, got %v", err)
+	}
+
+	if len(changed) != 3 {
+		t.Fatalf("expected 3 changed assignments, got %d", len(changed))
+	}
+
+	renamed := doc.Get("Z")
+	if renamed == nil || doc.Has("A") {
+		t.Fatal("expected A to be renamed to Z")
+	}
+
+	if renamed.DocumentationSummary() != "docs for A" {
+		t.Fatalf("expected comments to be retained, got %q", renamed.DocumentationSummary())
+	}
+
+	for key, expected := range map[string]string{"B": "${Z:-2}", "C": "${A}", "D": "$Z$Z"} {
+		if got := doc.Get(key).Literal; got != expected {
+			t.Fatalf("expected %s literal to be %q, got %q", key, expected, got)
+		}
+	}
+
+	if _, ok := renamed.Dependents["B"]; !ok {
+		t.Fatal("expected dependents to be recomputed after rename")
+	}
+
+	if _, err := doc.Rename(t.Context(), "Z", "B"); err == nil {
+		t.Fatal("expected error when renaming to an existing KEY")
+	}
+}
+
+func TestDocumentRenameRewritesNestedReferences(t *testing.T) {
+	t.Parallel()
+
+	doc := parseDocument(t, strings.Join([]string{
+		"A=1",
+		`B="${OTHER:-${A}}"`,
+		`C="${OTHER:+prefix-$A}"`,
+	}, "\n"))
+
+	changed, err := doc.Rename(t.Context(), "A", "Z")
+	if err != nil {
+		t.Fatalf("expected rename to succeed, got %v", err)
+	}
+
+	if len(changed) != 3 {
+		t.Fatalf("expected 3 changed assignments, got %d", len(changed))
+	}
+
+	for key, expected := range map[string]string{"B": "${OTHER:-${Z}}", "C": "${OTHER:+prefix-$Z}"} {
+		if got := doc.Get(key).Literal; got != expected {
+			t.Fatalf("expected %s literal to be %q, got %q", key, expected, got)
+		}
+	}
+}
+
+func TestDocumentRenameRejectsInvalidNames(t *testing.T) {
+	t.Parallel()
+
+	doc := parseDocument(t, "A=1\nB=$A\n")
+
+	for _, name := range []string{"", "X Y", "X=Y", "#X", "X\nY"} {
+		if _, err := doc.Rename(t.Context(), "A", name); err == nil {
+			t.Fatalf("expected renaming to %q to fail", name)
+		}
+	}
+
+	if !doc.Has("A") || doc.Get("B").Literal != "$A" {
+		t.Fatal("expected the document to be unchanged after a rejected rename")
+	}
+}
+
+func TestDocumentGetIndexFollowsMutations(t *testing.T) {
+	t.Parallel()
+
+	doc := parseDocument(t, strings.Join([]string{
+		"#A=disabled",
+		"A=enabled",
+		"B=2",
+	}, "\n"))
+
+	// The first assignment wins, just like it does in the file
+	if got := doc.Get("A"); got == nil || got.Literal != "disabled" {
+		t.Fatalf("expected the first (disabled) A, got %+v", got)
+	}
+
+	if _, err := doc.Delete("A"); err != nil {
+		t.Fatalf("expected A to be deleted, got %v", err)
+	}
+
+	if got := doc.Get("A"); got == nil || got.Literal != "enabled" {
+		t.Fatalf("expected the remaining A after delete, got %+v", got)
+	}
+
+	replacement := &ast.Assignment{Name: "B", Literal: "3", Enabled: true}
+	if err := doc.Replace(replacement); err != nil {
+		t.Fatalf("expected B to be replaced, got %v", err)
+	}
+
+	if doc.Get("B") != replacement {
+		t.Fatal("expected Get to return the replacement for B")
+	}
+
+	if _, err := doc.Rename(t.Context(), "B", "C"); err != nil {
+		t.Fatalf("expected B to be renamed, got %v", err)
+	}
+
+	if doc.Has("B") || doc.Get("C") != replacement {
+		t.Fatal("expected the index to follow the rename from B to C")
+	}
+
+	doc.Statements = append(doc.Statements, &ast.Assignment{Name: "D", Enabled: true})
+	doc.ReindexStatements()
+
+	if !doc.Has("D") {
+		t.Fatal("expected D to be found after reindexing")
+	}
+}
+
+func TestDocumentDuplicatesAndDedupe(t *testing.T) {
+	t.Parallel()
+
+	doc := parseDocument(t, strings.Join([]string{
+		"# @dottie/validate number",
+		"PORT=1",
+		"#DEBUG=true",
+		"",
+		"###",
+		"# group",
+		"###",
+		"",
+		"# @dottie/validate number",
+		"# overridden in CI",
+		"PORT=2",
+		"DEBUG=false",
+		"UNIQUE=1",
+	}, "\n"))
+
+	duplicates := doc.Duplicates()
+	if len(duplicates) != 2 || duplicates[0].Name != "PORT" || duplicates[1].Name != "DEBUG" {
+		t.Fatalf("expected PORT and DEBUG to be duplicates, got %+v", duplicates)
+	}
+
+	if got := duplicates[0].Keep(ast.KeepFirst).Literal; got != "1" {
+		t.Fatalf("expected first PORT to be kept, got %q", got)
+	}
+
+	if got := duplicates[1].Keep(ast.KeepEnabled).Literal; got != "false" {
+		t.Fatalf("expected enabled DEBUG to be kept, got %q", got)
+	}
+
+	doc.Dedupe(ast.KeepLast)
+
+	if len(doc.Duplicates()) != 0 {
+		t.Fatalf("expected no duplicates after dedupe, got %d", len(doc.Duplicates()))
+	}
+
+	port := doc.Get("PORT")
+	if port == nil || port.Literal != "2" {
+		t.Fatalf("expected the last PORT to be kept, got %+v", port)
+	}
+
+	var comments []string
+	for _, comment := range port.Comments {
+		comments = append(comments, comment.Value)
+	}
+
+	if strings.Join(comments, "\n") != "# @dottie/validate number\n# overridden in CI" {
+		t.Fatalf("expected comments to be merged without duplicates, got %q", comments)
+	}
+
+	if len(doc.Annotations) != 1 {
+		t.Fatalf("expected the duplicate annotation to be dropped, got %d annotations", len(doc.Annotations))
+	}
+
+	if got := len(doc.AllAssignments()); got != 3 {
+		t.Fatalf("expected 3 assignments left, got %d", got)
+	}
+}
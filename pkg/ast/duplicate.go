@@ -0,0 +1,137 @@
+package ast
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DuplicateKey is a KEY that is assigned more than once in a document, which is easy to miss
+// in larger files, and confusing since [Document.Get] returns the first while shells use the last.
+type DuplicateKey struct {
+	Name        string        `json:"key"`         // Name of the key
+	Assignments []*Assignment `json:"assignments"` // All assignments of the key, in the order they appear in the file
+}
+
+// Keep returns the assignment that is kept when resolving the duplicate with [policy]
+func (d *DuplicateKey) Keep(policy DedupePolicy) *Assignment {
+	switch policy {
+	case KeepFirst:
+		return d.Assignments[0]
+
+	case KeepEnabled:
+		// Prefer the enabled assignment a shell would use, which is the last one
+		for _, assignment := range slices.Backward(d.Assignments) {
+			if assignment.Enabled {
+				return assignment
+			}
+		}
+
+		fallthrough
+
+	case KeepLast:
+		return d.Assignments[len(d.Assignments)-1]
+
+	default:
+		panic(fmt.Errorf("unexpected ast.DedupePolicy value: %d", policy))
+	}
+}
+
+// DedupePolicy decides which of the assignments of a [DuplicateKey] is kept by [Document.Dedupe]
+type DedupePolicy uint
+
+const (
+	KeepFirst   DedupePolicy = iota // Keep the first assignment, the one [Document.Get] returns
+	KeepLast                        // Keep the last assignment, the one a shell would use
+	KeepEnabled                     // Keep the last enabled assignment, or the last one if they are all disabled
+)
+
+func DedupePolicyFromString(in string) (DedupePolicy, error) {
+	switch in {
+	case "first":
+		return KeepFirst, nil
+
+	case "last":
+		return KeepLast, nil
+
+	case "enabled":
+		return KeepEnabled, nil
+
+	default:
+		return 0, fmt.Errorf("invalid dedupe policy [ %s ], must be one of: first, last, enabled", in)
+	}
+}
+
+func (p DedupePolicy) String() string {
+	switch p {
+	case KeepFirst:
+		return "first"
+
+	case KeepLast:
+		return "last"
+
+	case KeepEnabled:
+		return "enabled"
+
+	default:
+		panic(fmt.Errorf("unexpected ast.DedupePolicy value: %d", p))
+	}
+}
+
+// Duplicates returns the KEYs that are assigned more than once in the document (enabled or not),
+// ordered by where they first appear.
+//
+// They are recorded when the document is (re)indexed, see [Document.ReindexStatements].
+func (document *Document) Duplicates() []*DuplicateKey {
+	if document.index == nil {
+		document.ReindexStatements()
+	}
+
+	return document.duplicates
+}
+
+// Dedupe resolves all [Document.Duplicates] by keeping one assignment per KEY (decided by [policy]),
+// and removing the others. Comments of the removed assignments are merged into the kept one,
+// skipping comments the kept assignment already has.
+//
+// The resolved duplicates are returned, and the caller is responsible for calling
+// [Document.Initialize] afterwards to refresh dependency information.
+func (document *Document) Dedupe(policy DedupePolicy) []*DuplicateKey {
+	duplicates := document.Duplicates()
+
+	for _, duplicate := range duplicates {
+		keep := duplicate.Keep(policy)
+
+		var (
+			comments []*Comment
+			dropped  []*Comment
+		)
+
+		for _, assignment := range duplicate.Assignments {
+			for _, comment := range assignment.Comments {
+				if slices.ContainsFunc(comments, func(existing *Comment) bool { return existing.Value == comment.Value }) {
+					dropped = append(dropped, comment)
+
+					continue
+				}
+
+				comment.Group = keep.Group
+				comments = append(comments, comment)
+			}
+
+			if assignment != keep {
+				assignment.Comments = nil
+				document.remove(assignment)
+			}
+		}
+
+		keep.Comments = comments
+
+		document.Annotations = slices.DeleteFunc(document.Annotations, func(comment *Comment) bool {
+			return slices.Contains(dropped, comment)
+		})
+	}
+
+	document.ReindexStatements()
+
+	return duplicates
+}
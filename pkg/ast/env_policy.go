@@ -0,0 +1,206 @@
+package ast
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// EnvPolicyAnnotation is the document annotation that configures the [EnvPolicy] of a file
+const EnvPolicyAnnotation = "dottie/env-policy"
+
+// EnvPolicyMode controls if, and in which order, the process environment is consulted when interpolating variables
+type EnvPolicyMode uint
+
+const (
+	EnvFirst     EnvPolicyMode = iota // The process environment takes precedence over the document, like a shell would do
+	FileFirst                         // The document takes precedence, the process environment is used for KEYs not in the document
+	FileOnly                          // The process environment is never consulted
+	EnvAllowList                      // Only the allowed process environment variables are consulted, and they take precedence over the document
+)
+
+// EnvPolicy controls how interpolation resolves variables that are both in the document and the process environment
+type EnvPolicy struct {
+	Mode  EnvPolicyMode
+	Allow []string // The process environment variables an [EnvAllowList] policy may use
+}
+
+// DefaultEnvPolicy is used when neither [ContextWithEnvPolicy] nor the "@dottie/env-policy" annotation configures a policy
+var DefaultEnvPolicy = EnvPolicy{Mode: EnvFirst}
+
+// EnvPolicyFromString parses a policy in the format "env-first", "file-first", "file-only" or "allow:NAME[,NAME...]"
+func EnvPolicyFromString(in string) (EnvPolicy, error) {
+	in = strings.TrimSpace(in)
+
+	switch in {
+	case "env-first":
+		return EnvPolicy{Mode: EnvFirst}, nil
+
+	case "file-first":
+		return EnvPolicy{Mode: FileFirst}, nil
+
+	case "file-only":
+		return EnvPolicy{Mode: FileOnly}, nil
+	}
+
+	if names, ok := strings.CutPrefix(in, "allow:"); ok {
+		policy := EnvPolicy{Mode: EnvAllowList}
+
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); len(name) > 0 {
+				policy.Allow = append(policy.Allow, name)
+			}
+		}
+
+		if len(policy.Allow) == 0 {
+			return EnvPolicy{}, fmt.Errorf("invalid env policy [ %s ], [allow:] requires at least one variable name", in)
+		}
+
+		return policy, nil
+	}
+
+	return EnvPolicy{}, fmt.Errorf("invalid env policy [ %s ], must be one of: env-first, file-first, file-only, allow:NAME[,NAME...]", in)
+}
+
+func (p EnvPolicy) String() string {
+	switch p.Mode {
+	case EnvFirst:
+		return "env-first"
+
+	case FileFirst:
+		return "file-first"
+
+	case FileOnly:
+		return "file-only"
+
+	case EnvAllowList:
+		return "allow:" + strings.Join(p.Allow, ",")
+
+	default:
+		panic(fmt.Errorf("unexpected ast.EnvPolicyMode value: %d", p.Mode))
+	}
+}
+
+// LookupEnv returns the process environment variable [name], if the policy allows using it
+func (p EnvPolicy) LookupEnv(name string) (string, bool) {
+	switch p.Mode {
+	case FileOnly:
+		return "", false
+
+	case EnvAllowList:
+		if !slices.Contains(p.Allow, name) {
+			return "", false
+		}
+
+	case EnvFirst, FileFirst:
+	}
+
+	return os.LookupEnv(name)
+}
+
+// envFirst reports if the process environment takes precedence over the document
+func (p EnvPolicy) envFirst() bool {
+	return p.Mode == EnvFirst || p.Mode == EnvAllowList
+}
+
+type envPolicyContextKey int
+
+const envPolicyKey envPolicyContextKey = iota
+
+// ContextWithEnvPolicy returns a context where interpolation uses the [policy],
+// regardless of the "@dottie/env-policy" annotation in the document
+func ContextWithEnvPolicy(ctx context.Context, policy EnvPolicy) context.Context {
+	return context.WithValue(ctx, envPolicyKey, policy)
+}
+
+// EnvPolicyFromContext returns the [EnvPolicy] configured with [ContextWithEnvPolicy], if any
+func EnvPolicyFromContext(ctx context.Context) (EnvPolicy, bool) {
+	policy, ok := ctx.Value(envPolicyKey).(EnvPolicy)
+
+	return policy, ok
+}
+
+// EnvPolicy returns the policy configured with [ContextWithEnvPolicy], or by the
+// "@dottie/env-policy" annotation in the document, or [DefaultEnvPolicy]
+func (doc *Document) EnvPolicy(ctx context.Context) (EnvPolicy, error) {
+	if policy, ok := EnvPolicyFromContext(ctx); ok {
+		return policy, nil
+	}
+
+	values := doc.GetConfigs(EnvPolicyAnnotation)
+	if len(values) == 0 {
+		return DefaultEnvPolicy, nil
+	}
+
+	return EnvPolicyFromString(values[0])
+}
+
+// VariableOrigin is where interpolation found the value of a variable
+type VariableOrigin uint
+
+const (
+	OriginUnset       VariableOrigin = iota // The variable was not found
+	OriginEnvironment                       // The variable was found in the process environment
+	OriginDocument                          // The variable was found in the document
+)
+
+func (o VariableOrigin) String() string {
+	switch o {
+	case OriginUnset:
+		return "unset"
+
+	case OriginEnvironment:
+		return "environment"
+
+	case OriginDocument:
+		return "file"
+
+	default:
+		panic(fmt.Errorf("unexpected ast.VariableOrigin value: %d", o))
+	}
+}
+
+// LookupVariable returns the value of the variable [name] as seen by interpolation of the [target] assignment,
+// and where the value was found according to the [policy]
+func (doc *Document) LookupVariable(policy EnvPolicy, target *Assignment, name string) (string, VariableOrigin) {
+	if policy.envFirst() {
+		if val, ok := policy.LookupEnv(name); ok {
+			return val, OriginEnvironment
+		}
+	}
+
+	if val, ok := doc.lookupAssignment(target, name); ok {
+		return val, OriginDocument
+	}
+
+	if !policy.envFirst() {
+		if val, ok := policy.LookupEnv(name); ok {
+			return val, OriginEnvironment
+		}
+	}
+
+	return "", OriginUnset
+}
+
+// lookupAssignment returns the interpolated value of the KEY [name], if the [target] assignment can access it
+func (doc *Document) lookupAssignment(target *Assignment, name string) (string, bool) {
+	assignment := doc.Get(name)
+	if assignment == nil {
+		return "", false
+	}
+
+	// If the assignment we found is on a index (sorted) *after* the target
+	// assignment, don't count it as found, since all normal shell interpolation
+	// are handled in order (e.g. line 5 can't use a variable from line 10)
+	if assignment.Position.Index >= target.Position.Index {
+		return "", false
+	}
+
+	if !assignment.Enabled {
+		return "", false
+	}
+
+	return assignment.Interpolated, true
+}
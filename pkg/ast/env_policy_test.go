@@ -0,0 +1,85 @@
+package ast_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/token"
+)
+
+func TestEnvPolicyFromString(t *testing.T) {
+	t.Parallel()
+
+	for _, in := range []string{"env-first", "file-first", "file-only", "allow:HOME,PORT"} {
+		policy, err := ast.EnvPolicyFromString(in)
+		if err != nil {
+			t.Fatalf("expected [%s] to be a valid policy, got %q", in, err.Error())
+		}
+
+		if policy.String() != in {
+			t.Fatalf("expected policy [%s] to round-trip, got [%s]", in, policy.String())
+		}
+	}
+
+	for _, in := range []string{"", "env", "allow:", "allow: , "} {
+		if _, err := ast.EnvPolicyFromString(in); err == nil {
+			t.Fatalf("expected [%s] to be an invalid policy", in)
+		}
+	}
+}
+
+func TestInterpolationMapperHonorsEnvPolicy(t *testing.T) {
+	t.Setenv("DOTTIE_POLICY_PORT", "5432")
+	t.Setenv("DOTTIE_POLICY_ONLY_ENV", "env")
+
+	port := &ast.Assignment{
+		Name:         "DOTTIE_POLICY_PORT",
+		Literal:      "3306",
+		Interpolated: "3306",
+		Enabled:      true,
+		Quote:        token.NoQuote,
+		Position:     ast.Position{File: "test.env", Line: 1, Index: 0},
+	}
+
+	target := &ast.Assignment{
+		Name:         "URL",
+		Literal:      "$DOTTIE_POLICY_PORT",
+		Interpolated: "$DOTTIE_POLICY_PORT",
+		Enabled:      true,
+		Quote:        token.NoQuote,
+		Position:     ast.Position{File: "test.env", Line: 2, Index: 1},
+	}
+
+	doc := ast.NewDocument()
+	doc.Statements = []ast.Statement{port, target}
+
+	doc.Initialize(context.Background())
+
+	tests := []struct {
+		policy  string
+		name    string
+		want    string
+		wantSet bool
+	}{
+		{policy: "env-first", name: "DOTTIE_POLICY_PORT", want: "5432", wantSet: true},
+		{policy: "file-first", name: "DOTTIE_POLICY_PORT", want: "3306", wantSet: true},
+		{policy: "file-first", name: "DOTTIE_POLICY_ONLY_ENV", want: "env", wantSet: true},
+		{policy: "file-only", name: "DOTTIE_POLICY_PORT", want: "3306", wantSet: true},
+		{policy: "file-only", name: "DOTTIE_POLICY_ONLY_ENV", want: "", wantSet: false},
+		{policy: "allow:DOTTIE_POLICY_PORT", name: "DOTTIE_POLICY_PORT", want: "5432", wantSet: true},
+		{policy: "allow:DOTTIE_POLICY_PORT", name: "DOTTIE_POLICY_ONLY_ENV", want: "", wantSet: false},
+	}
+
+	for _, tt := range tests {
+		policy, err := ast.EnvPolicyFromString(tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, ok := doc.InterpolationMapper(ast.ContextWithEnvPolicy(context.Background(), policy), target)(tt.name)
+		if got != tt.want || ok != tt.wantSet {
+			t.Fatalf("policy [%s] resolved [%s] to (%q, %v), expected (%q, %v)", tt.policy, tt.name, got, ok, tt.want, tt.wantSet)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package ast
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// GraphNode is a KEY in the reference [Graph]
+type GraphNode struct {
+	Name     string `json:"name"`
+	Position string `json:"position,omitempty"` // Where the KEY is assigned (empty for missing KEYs)
+	Enabled  bool   `json:"enabled"`            // The KEY is assigned, and not commented out
+	Missing  bool   `json:"missing"`            // The KEY is referenced, but not assigned in the document
+	Cycle    bool   `json:"cycle"`              // The KEY is part of a reference cycle
+}
+
+// GraphEdge is a reference from the [From] KEY to the [To] KEY it depends on
+type GraphEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Missing  bool   `json:"missing"`  // The [To] KEY is not assigned in the document
+	Disabled bool   `json:"disabled"` // The [To] KEY is commented out
+	Cycle    bool   `json:"cycle"`    // The reference is part of a cycle
+}
+
+// Graph is the interpolation reference graph of a document, built from the
+// [Assignment.Dependencies] computed by [Document.Initialize]
+type Graph struct {
+	Nodes  []*GraphNode `json:"nodes"`  // KEYs in document order, followed by the missing KEYs in the order they are first referenced
+	Edges  []*GraphEdge `json:"edges"`  // References in document order, sorted by name for each KEY
+	Cycles [][]string   `json:"cycles"` // KEYs referencing each other, directly or through other KEYs
+}
+
+// Graph returns the reference graph of the document
+func (doc *Document) Graph() *Graph {
+	graph := &Graph{
+		Nodes:  []*GraphNode{},
+		Edges:  []*GraphEdge{},
+		Cycles: [][]string{},
+	}
+
+	nodes := map[string]*GraphNode{}
+
+	for _, assignment := range doc.AllAssignments() {
+		if _, ok := nodes[assignment.Name]; ok {
+			continue
+		}
+
+		// Duplicate KEYs resolve to the same assignment as interpolation does
+		assignment = doc.Get(assignment.Name)
+
+		node := &GraphNode{
+			Name:     assignment.Name,
+			Position: assignment.Position.String(),
+			Enabled:  assignment.Enabled,
+		}
+
+		nodes[node.Name] = node
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	for _, node := range slices.Clone(graph.Nodes) {
+		assignment := doc.Get(node.Name)
+
+		for _, name := range slices.Sorted(maps.Keys(assignment.Dependencies)) {
+			dependency, ok := nodes[name]
+			if !ok {
+				dependency = &GraphNode{Name: name, Missing: true}
+
+				nodes[name] = dependency
+				graph.Nodes = append(graph.Nodes, dependency)
+			}
+
+			graph.Edges = append(graph.Edges, &GraphEdge{
+				From:     node.Name,
+				To:       name,
+				Missing:  dependency.Missing,
+				Disabled: !dependency.Missing && !dependency.Enabled,
+			})
+		}
+	}
+
+	graph.markCycles()
+
+	return graph
+}
+
+// Neighborhood returns the part of the graph around the KEY [name]: the KEYs it depends on,
+// and the KEYs depending on it, directly or through other KEYs
+func (g *Graph) Neighborhood(name string) (*Graph, error) {
+	if !slices.ContainsFunc(g.Nodes, func(node *GraphNode) bool { return node.Name == name }) {
+		return nil, fmt.Errorf("key [ %s ] does not exists", name)
+	}
+
+	keep := map[string]bool{name: true}
+
+	g.walk(name, keep, func(edge *GraphEdge) (string, string) { return edge.From, edge.To })
+	g.walk(name, keep, func(edge *GraphEdge) (string, string) { return edge.To, edge.From })
+
+	neighborhood := &Graph{
+		Nodes:  []*GraphNode{},
+		Edges:  []*GraphEdge{},
+		Cycles: [][]string{},
+	}
+
+	for _, node := range g.Nodes {
+		if keep[node.Name] {
+			neighborhood.Nodes = append(neighborhood.Nodes, node)
+		}
+	}
+
+	for _, edge := range g.Edges {
+		if keep[edge.From] && keep[edge.To] {
+			neighborhood.Edges = append(neighborhood.Edges, edge)
+		}
+	}
+
+	for _, cycle := range g.Cycles {
+		if keep[cycle[0]] {
+			neighborhood.Cycles = append(neighborhood.Cycles, cycle)
+		}
+	}
+
+	return neighborhood, nil
+}
+
+// walk adds every KEY reachable from [name] to [seen], following the edges in the [direction] returned by the callback
+func (g *Graph) walk(name string, seen map[string]bool, direction func(*GraphEdge) (string, string)) {
+	queue := []string{name}
+	visited := map[string]bool{name: true}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, edge := range g.Edges {
+			from, to := direction(edge)
+			if from != current || visited[to] {
+				continue
+			}
+
+			visited[to] = true
+			seen[to] = true
+			queue = append(queue, to)
+		}
+	}
+}
+
+// markCycles finds the strongly connected components of the graph (using Tarjan's algorithm),
+// and marks the KEYs and references of those forming a cycle
+func (g *Graph) markCycles() {
+	var (
+		index   = map[string]int{}
+		lowLink = map[string]int{}
+		onStack = map[string]bool{}
+		stack   []string
+		counter int
+		visit   func(name string)
+	)
+
+	visit = func(name string) {
+		index[name] = counter
+		lowLink[name] = counter
+		counter++
+
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, edge := range g.Edges {
+			if edge.From != name {
+				continue
+			}
+
+			if _, ok := index[edge.To]; !ok {
+				visit(edge.To)
+				lowLink[name] = min(lowLink[name], lowLink[edge.To])
+			} else if onStack[edge.To] {
+				lowLink[name] = min(lowLink[name], index[edge.To])
+			}
+		}
+
+		if lowLink[name] != index[name] {
+			return
+		}
+
+		var component []string
+
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+
+			component = append(component, top)
+
+			if top == name {
+				break
+			}
+		}
+
+		selfReference := slices.ContainsFunc(g.Edges, func(edge *GraphEdge) bool { return edge.From == name && edge.To == name })
+
+		if len(component) > 1 || selfReference {
+			g.Cycles = append(g.Cycles, component)
+		}
+	}
+
+	for _, node := range g.Nodes {
+		if _, ok := index[node.Name]; !ok {
+			visit(node.Name)
+		}
+	}
+
+	for i, cycle := range g.Cycles {
+		// List the KEYs of each cycle in document order
+		members := map[string]bool{}
+		for _, name := range cycle {
+			members[name] = true
+		}
+
+		ordered := make([]string, 0, len(cycle))
+
+		for _, node := range g.Nodes {
+			if members[node.Name] {
+				node.Cycle = true
+				ordered = append(ordered, node.Name)
+			}
+		}
+
+		g.Cycles[i] = ordered
+
+		for _, edge := range g.Edges {
+			if members[edge.From] && members[edge.To] {
+				edge.Cycle = true
+			}
+		}
+	}
+
+	// Order the cycles by their first KEY in the document
+	position := map[string]int{}
+	for i, node := range g.Nodes {
+		position[node.Name] = i
+	}
+
+	slices.SortFunc(g.Cycles, func(a, b []string) int {
+		return position[a[0]] - position[b[0]]
+	})
+}
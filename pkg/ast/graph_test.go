@@ -0,0 +1,108 @@
+package ast_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/token"
+)
+
+func graphDocument(t *testing.T, lines ...[3]string) *ast.Document {
+	t.Helper()
+
+	doc := ast.NewDocument()
+
+	for i, line := range lines {
+		doc.Statements = append(doc.Statements, &ast.Assignment{
+			Name:         line[0],
+			Literal:      line[1],
+			Interpolated: line[1],
+			Enabled:      line[2] != "disabled",
+			Quote:        token.NoQuote,
+			Position:     ast.Position{File: "test.env", Line: uint(i + 1), Index: i},
+		})
+	}
+
+	doc.Initialize(context.Background())
+
+	return doc
+}
+
+func edgeNames(graph *ast.Graph) []string {
+	var edges []string
+
+	for _, edge := range graph.Edges {
+		edges = append(edges, edge.From+"->"+edge.To)
+	}
+
+	return edges
+}
+
+func TestGraphMarksMissingDisabledAndCycles(t *testing.T) {
+	t.Parallel()
+
+	doc := graphDocument(t,
+		[3]string{"HOST", "localhost"},
+		[3]string{"URL", "${HOST}:${PORT}"},
+		[3]string{"OLD", "x", "disabled"},
+		[3]string{"USES_OLD", "$OLD"},
+		[3]string{"A", "$B"},
+		[3]string{"B", "$A"},
+		[3]string{"SELF", "$SELF"},
+	)
+
+	graph := doc.Graph()
+
+	if edges := []string{"URL->HOST", "URL->PORT", "USES_OLD->OLD", "A->B", "B->A", "SELF->SELF"}; !slices.Equal(edgeNames(graph), edges) {
+		t.Fatalf("expected edges %v, got %v", edges, edgeNames(graph))
+	}
+
+	if last := graph.Nodes[len(graph.Nodes)-1]; last.Name != "PORT" || !last.Missing {
+		t.Fatalf("expected the missing [PORT] to be the last node, got %+v", last)
+	}
+
+	if !graph.Edges[1].Missing || !graph.Edges[2].Disabled || graph.Edges[0].Missing || graph.Edges[0].Disabled {
+		t.Fatalf("expected only the edges to [PORT] and [OLD] to be missing and disabled, got %+v", graph.Edges[:3])
+	}
+
+	if len(graph.Cycles) != 2 || !slices.Equal(graph.Cycles[0], []string{"A", "B"}) || !slices.Equal(graph.Cycles[1], []string{"SELF"}) {
+		t.Fatalf("expected the cycles [A B] and [SELF], got %v", graph.Cycles)
+	}
+
+	for _, edge := range graph.Edges {
+		if edge.Cycle != (edge.From == "A" || edge.From == "B" || edge.From == "SELF") {
+			t.Fatalf("expected only edges in a cycle to be marked, got %+v", edge)
+		}
+	}
+}
+
+func TestGraphNeighborhood(t *testing.T) {
+	t.Parallel()
+
+	doc := graphDocument(t,
+		[3]string{"HOST", "localhost"},
+		[3]string{"PORT", "5432"},
+		[3]string{"URL", "${HOST}:${PORT}"},
+		[3]string{"DSN", "db://${URL}"},
+		[3]string{"UNRELATED", "${PORT}"},
+	)
+
+	graph, err := doc.Graph().Neighborhood("URL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if edges := []string{"URL->HOST", "URL->PORT", "DSN->URL"}; !slices.Equal(edgeNames(graph), edges) {
+		t.Fatalf("expected edges %v, got %v", edges, edgeNames(graph))
+	}
+
+	if len(graph.Nodes) != 4 {
+		t.Fatalf("expected [UNRELATED] to be left out, got %d nodes", len(graph.Nodes))
+	}
+
+	if _, err := doc.Graph().Neighborhood("NOPE"); err == nil {
+		t.Fatal("expected an unknown KEY to fail")
+	}
+}
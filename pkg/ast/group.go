@@ -9,9 +9,17 @@ import (
 )
 
 type Group struct {
-	Name       string      `json:"name"`       // Name of the group (within the header)
-	Statements []Statement `json:"statements"` // Statements within the group
-	Position   Position    `json:"position"`   // Positional information about the group
+	Name       string      `json:"name"`        // Name of the group (within the header)
+	Statements []Statement `json:"statements"`  // Statements within the group
+	Position   Position    `json:"position"`    // Positional information about the group
+	HeaderSpan Span        `json:"header_span"` // Location of the group header (from the first to the last banner line)
+
+	source *groupSource // The original header from the source file, see [Group.Source]
+}
+
+type groupSource struct {
+	text string
+	name string
 }
 
 func (g *Group) statementNode() {
@@ -45,6 +53,17 @@ func (g *Group) String() string {
 	return strings.TrimPrefix(g.Name, "# ")
 }
 
+// Source returns the group header exactly as it was in the source file.
+//
+// The boolean is false if the original header is unknown, or the group has been renamed since.
+func (g *Group) Source() (string, bool) {
+	if g.source == nil || g.source.name != g.Name {
+		return "", false
+	}
+
+	return g.source.text, true
+}
+
 func (g *Group) Assignments() []*Assignment {
 	var assignments []*Assignment
 
@@ -2,6 +2,8 @@ package ast
 
 import (
 	"fmt"
+
+	"go.uber.org/multierr"
 )
 
 func ContextualError(stmt Statement, err error) error {
@@ -26,3 +28,23 @@ func ContextualError(stmt Statement, err error) error {
 
 	return fmt.Errorf("%w (%s)", err, pos)
 }
+
+// UniqueErrors removes the errors with the same message from the combined [err], since interpolating
+// each assignment on its own can report the same error more than once (e.g. for a cyclic dependency)
+func UniqueErrors(err error) error {
+	var (
+		unique error
+		seen   = map[string]struct{}{}
+	)
+
+	for _, err := range multierr.Errors(err) {
+		if _, ok := seen[err.Error()]; ok {
+			continue
+		}
+
+		seen[err.Error()] = struct{}{}
+		unique = multierr.Append(unique, err)
+	}
+
+	return unique
+}
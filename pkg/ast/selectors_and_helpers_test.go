@@ -6,6 +6,7 @@ import (
 
 	"github.com/jippi/dottie/pkg/ast"
 	"github.com/jippi/dottie/pkg/token"
+	"go.uber.org/multierr"
 )
 
 func TestExcludeComments(t *testing.T) {
@@ -153,6 +154,19 @@ func TestContextualError(t *testing.T) {
 	}
 }
 
+func TestUniqueErrors(t *testing.T) {
+	t.Parallel()
+
+	if got := ast.UniqueErrors(nil); got != nil {
+		t.Fatalf("expected nil when error is nil, got %v", got)
+	}
+
+	got := multierr.Errors(ast.UniqueErrors(multierr.Combine(errors.New("a"), errors.New("b"), errors.New("a"))))
+	if len(got) != 2 || got[0].Error() != "a" || got[1].Error() != "b" {
+		t.Fatalf("expected the errors [a b], got %v", got)
+	}
+}
+
 func TestNewlineIsAndType(t *testing.T) {
 	t.Parallel()
 
@@ -17,18 +17,49 @@ type StatementCollection interface {
 	GetAssignmentIndex(name string) (int, *Assignment)
 }
 
+// Position describes where a statement is in the file.
+//
+// [Column] and [Offset] point to the start of the statement's own source text on [Line],
+// [EndColumn] and [EndOffset] right after its end. Columns are 1-based and counted in
+// characters, offsets are 0-based and counted in bytes.
 type Position struct {
 	Index     int    `json:"index"`
 	File      string `json:"file"`
 	Line      uint   `json:"line"`
 	FirstLine uint   `json:"first_line"`
 	LastLine  uint   `json:"last_line"`
+	Column    int    `json:"column"`
+	EndColumn int    `json:"end_column"`
+	Offset    int    `json:"offset"`
+	EndOffset int    `json:"end_offset"`
 }
 
 func (p Position) String() string {
+	if p.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+
 	return fmt.Sprintf("%s:%d", p.File, p.Line)
 }
 
+// Span is the location of a part of a statement, e.g. the value of an assignment.
+//
+// Columns are 1-based and counted in characters, offsets are 0-based and counted in bytes.
+// The end column and end offset point right after the last character of the span.
+type Span struct {
+	Line      uint `json:"line"`
+	Column    int  `json:"column"`
+	EndLine   uint `json:"end_line"`
+	EndColumn int  `json:"end_column"`
+	Offset    int  `json:"offset"`
+	EndOffset int  `json:"end_offset"`
+}
+
+// IsSingleLine returns if the span starts and ends on the same line.
+func (s Span) IsSingleLine() bool {
+	return s.Line == s.EndLine
+}
+
 type ValidationError struct {
 	WrappedError any
 	Assignment   *Assignment
@@ -36,6 +36,11 @@ const (
 	// Normally comments are only applied to *NEW* keys and not on existing ones.
 	UpdateComments
 
+	// Replace the inline (trailing) comment on *existing* Assignments.
+	//
+	// Normally the inline comment is only applied to *NEW* keys and not on existing ones.
+	UpdateInlineComment
+
 	// Only here to make iteration over the Settings list easier, is not used externally and have no special meaning.
 	// See: settings.name()
 	maxKey
@@ -57,7 +62,7 @@ func (bitmask Setting) Has(setting Setting) bool {
 func (setting Setting) String() string {
 	// Single key bitmask
 	switch setting {
-	case SkipIfSame, SkipIfExists, SkipIfSet, Validate, ErrorIfMissing, UpdateComments, SkipIfEmpty:
+	case SkipIfSame, SkipIfExists, SkipIfSet, Validate, ErrorIfMissing, UpdateComments, UpdateInlineComment, SkipIfEmpty:
 		return fmt.Sprintf("upsert.Setting<%s>", setting.name())
 	case maxKey:
 	}
@@ -98,6 +103,9 @@ func (setting Setting) name() string {
 	case UpdateComments:
 		return "ReplaceComments"
 
+	case UpdateInlineComment:
+		return "ReplaceInlineComment"
+
 	case maxKey:
 	}
 
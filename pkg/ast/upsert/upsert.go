@@ -102,6 +102,11 @@ func (u *Upserter) Upsert(ctx context.Context, input *ast.Assignment) (*ast.Assi
 		existing.Comments = input.Comments
 	}
 
+	// Replace the inline comment on the assignment if the Setting is on
+	if u.settings.Has(UpdateInlineComment) {
+		existing.InlineComment = input.InlineComment
+	}
+
 	existing.Enabled = input.Enabled
 	existing.Literal = input.Literal
 	existing.Interpolated = input.Literal
@@ -115,7 +120,7 @@ func (u *Upserter) Upsert(ctx context.Context, input *ast.Assignment) (*ast.Assi
 	// Render and parse back the Statement to ensure annotations and such are properly handled
 	thing := u.document.AllAssignments()[:existing.Position.Index+1]
 	content := render.NewFormatter().Statement(ctx, thing).String()
-	scan := scanner.New(content)
+	scan := scanner.New(content, scanner.WithLimits(scanner.LimitsFromContext(ctx)))
 
 	slogctx.Debug(ctx, "memory://tmp/upsert", tui.StringDump("rendered_content", content))
 
@@ -162,17 +167,19 @@ func (u *Upserter) Upsert(ctx context.Context, input *ast.Assignment) (*ast.Assi
 func (u *Upserter) createAndInsert(ctx context.Context, input *ast.Assignment) (*ast.Assignment, error) {
 	// Create the new newAssignment
 	newAssignment := &ast.Assignment{
-		Comments: input.Comments,
-		Enabled:  input.Enabled,
-		Literal:  input.Literal,
-		Name:     input.Name,
-		Quote:    input.Quote,
+		Comments:      input.Comments,
+		Enabled:       input.Enabled,
+		Exported:      input.Exported,
+		InlineComment: input.InlineComment,
+		Literal:       input.Literal,
+		Name:          input.Name,
+		Quote:         input.Quote,
 	}
 
 	slogctx.Debug(ctx, "createAndInsert: input.Literal", tui.StringDump("literal", newAssignment.Literal))
 
 	content := render.NewFormatter().Statement(ctx, newAssignment).String()
-	scan := scanner.New(content)
+	scan := scanner.New(content, scanner.WithLimits(scanner.LimitsFromContext(ctx)))
 
 	slogctx.Debug(ctx, "createAndInsert: content", tui.StringDump("rendered_content", content))
 
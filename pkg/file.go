@@ -5,6 +5,8 @@ import (
 	"errors"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/jippi/dottie/pkg/ast"
 	"github.com/jippi/dottie/pkg/parser"
@@ -12,45 +14,230 @@ import (
 	"github.com/jippi/dottie/pkg/scanner"
 )
 
-func Load(ctx context.Context, filename string) (*ast.Document, error) {
+func Load(ctx context.Context, filename string, options ...parser.Option) (*ast.Document, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	return Parse(ctx, file, filename)
+	return Parse(ctx, file, filename, options...)
 }
 
+type contextKey int
+
+const (
+	preserveFormattingKey contextKey = iota
+	layersKey
+)
+
+// preserveFormattingAnnotation is the document annotation that enables [WithPreserveFormatting] for a file
+const preserveFormattingAnnotation = "dottie/preserve-formatting"
+
+// WithPreserveFormatting returns a context where [Save] keeps the original formatting of the file,
+// and only renders the statements that have been changed (or added).
+func WithPreserveFormatting(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, preserveFormattingKey, enabled)
+}
+
+// PreserveFormatting reports if [Save] will keep the original formatting of the document, either because
+// of [WithPreserveFormatting] or the document having a "@dottie/preserve-formatting" annotation.
+func PreserveFormatting(ctx context.Context, doc *ast.Document) bool {
+	if enabled, ok := ctx.Value(preserveFormattingKey).(bool); ok && enabled {
+		return true
+	}
+
+	value, err := doc.GetConfig(preserveFormattingAnnotation)
+
+	return err == nil && value != "false"
+}
+
+// Save writes the document to [filename], either fully formatted or with
+// the original formatting preserved (see [PreserveFormatting]).
 func Save(ctx context.Context, filename string, doc *ast.Document) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
+	if PreserveFormatting(ctx, doc) {
+		return save(ctx, filename, doc, render.NewPreservingFormatter())
 	}
-	defer file.Close()
 
-	res := render.NewFormatter().Statement(ctx, doc)
+	return SaveFormatted(ctx, filename, doc)
+}
+
+// SaveFormatted writes the fully formatted document to [filename], regardless of [PreserveFormatting].
+func SaveFormatted(ctx context.Context, filename string, doc *ast.Document) error {
+	return save(ctx, filename, doc, render.NewFormatter())
+}
+
+func save(ctx context.Context, filename string, doc *ast.Document, renderer *render.Renderer) error {
+	res := renderer.Statement(ctx, doc)
 	if res.IsEmpty() {
 		return errors.New("the rendered .env file is unexpectedly 0 bytes long - please report this as a bug (unless your file is empty)")
 	}
 
-	_, err = file.WriteString(res.String())
+	unlock, err := Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	return err
+	return WriteFile(filename, []byte(res.String()), 0o600)
 }
 
-// Parse reads an env file from io.Reader, returning a map of keys and values.
-func Parse(ctx context.Context, r io.Reader, filename string) (*ast.Document, error) {
-	input, err := io.ReadAll(r)
+// WriteFile atomically replaces [filename] with [data] by writing it to a temporary file
+// in the same directory, and renaming it over [filename] once it's fully written to disk.
+//
+// The mode and ownership of the existing file is kept, new files are created with [perm]
+// (.env files tend to contain secrets, so they are created with 0600).
+func WriteFile(filename string, data []byte, perm os.FileMode) error {
+	path, err := resolvePath(filename)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	mode := perm
+
+	original, err := os.Stat(path)
+	switch {
+	case err == nil:
+		mode = original.Mode().Perm()
+
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	dir, base := filepath.Split(path)
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		// We might not be allowed to create files in the directory, only to change the file itself
+		if os.IsPermission(err) && original != nil {
+			return writeFileInPlace(path, data)
+		}
+
+		return err
 	}
 
-	return parser.
+	// Clean up the temporary file if we fail before it has been renamed
+	renamed := false
+
+	defer func() {
+		if !renamed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+
+	if err := tmp.Chmod(mode); err != nil {
+		return err
+	}
+
+	if original != nil {
+		if err := copyOwner(tmp, original); err != nil {
+			return err
+		}
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		if isReplaceUnsupported(err) {
+			return writeFileInPlace(path, data)
+		}
+
+		return err
+	}
+
+	renamed = true
+
+	return syncDir(dir)
+}
+
+// writeFileInPlace is the fallback for when [filename] can't be replaced atomically
+func writeFileInPlace(filename string, data []byte) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
+
+// Parse reads an env file from io.Reader, returning a map of keys and values.
+//
+// The input is scanned as it's read, within the [scanner.Limits] configured
+// with [scanner.ContextWithLimits] (or [scanner.DefaultLimits]).
+//
+// The original text of the statements is only kept when [PreserveFormatting] needs it. When it's enabled
+// by the "@dottie/preserve-formatting" annotation, the input is read again, which requires an [io.Seeker].
+func Parse(ctx context.Context, r io.Reader, filename string, options ...parser.Option) (*ast.Document, error) {
+	var (
+		source *strings.Builder
+		input  = r
+	)
+
+	if enabled, ok := ctx.Value(preserveFormattingKey).(bool); ok && enabled {
+		source = &strings.Builder{}
+		input = io.TeeReader(r, source)
+	}
+
+	// Remember where the input starts, in case it must be read again for the annotation
+	seeker, seekable := r.(io.Seeker)
+
+	var start int64
+
+	if seekable && source == nil {
+		var err error
+
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+
+	document, err := parser.
 		New(
 			ctx,
-			scanner.New(string(input)),
+			scanner.NewReader(input, scanner.WithLimits(scanner.LimitsFromContext(ctx))),
 			filename,
+			options...,
 		).
 		Parse(ctx)
+
+	switch {
+	case document == nil:
+
+	case source != nil:
+		document.SetSource(source.String())
+
+	case seekable && PreserveFormatting(ctx, document):
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			break
+		}
+
+		if text, err := io.ReadAll(r); err == nil {
+			document.SetSource(string(text))
+		}
+	}
+
+	return document, err
 }
@@ -1,13 +1,24 @@
 package pkg_test
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	pkg "github.com/jippi/dottie/pkg"
 	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/scanner"
 )
 
 func TestParseAndRoundTripSaveLoad(t *testing.T) {
@@ -50,3 +61,305 @@ func TestSaveEmptyDocumentReturnsError(t *testing.T) {
 		t.Fatal("expected Save to fail for empty document")
 	}
 }
+
+func TestSavePreservesFileMode(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, ".env")
+
+	if err := os.WriteFile(filename, []byte("A=1\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := pkg.Load(context.Background(), filename)
+	if err != nil {
+		t.Fatalf("expected Load to succeed, got %v", err)
+	}
+
+	doc.Get("A").SetLiteral(context.Background(), "2")
+
+	if err := pkg.Save(context.Background(), filename, doc); err != nil {
+		t.Fatalf("expected Save to succeed, got %v", err)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o640 {
+		t.Fatalf("expected file mode 0640 to be preserved, got %#o", info.Mode().Perm())
+	}
+
+	// Only the .env file should be left, no temporary or lock files
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected only the saved file in the directory, got %d entries", len(entries))
+	}
+}
+
+func TestSaveCreatesNewFilesWithRestrictedMode(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+
+	doc, err := pkg.Parse(context.Background(), strings.NewReader("A=1\n"), "input.env")
+	if err != nil {
+		t.Fatalf("expected Parse to succeed, got %v", err)
+	}
+
+	filename := filepath.Join(t.TempDir(), ".env")
+	if err := pkg.Save(context.Background(), filename, doc); err != nil {
+		t.Fatalf("expected Save to succeed, got %v", err)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected new file to have mode 0600, got %#o", info.Mode().Perm())
+	}
+}
+
+func TestSaveThroughSymlinkReplacesTarget(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.env")
+	link := filepath.Join(dir, ".env")
+
+	if err := os.WriteFile(target, []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	doc, err := pkg.Load(context.Background(), link)
+	if err != nil {
+		t.Fatalf("expected Load to succeed, got %v", err)
+	}
+
+	doc.Get("A").SetLiteral(context.Background(), "2")
+
+	if err := pkg.Save(context.Background(), link, doc); err != nil {
+		t.Fatalf("expected Save to succeed, got %v", err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Fatal("expected the symlink to be kept")
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(content), "A=2") {
+		t.Fatalf("expected the symlink target to be updated, got %q", content)
+	}
+}
+
+// TestLockPreventsLostUpdates runs multiple processes incrementing the same counter
+// in a .env file, which only adds up if they don't overwrite each others changes.
+func TestLockPreventsLostUpdates(t *testing.T) {
+	t.Parallel()
+
+	const processes = 8
+
+	filename := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(filename, []byte("COUNTER=0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, processes)
+
+	for range processes {
+		wg.Go(func() {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+			cmd.Env = append(os.Environ(), "DOTTIE_LOCK_HELPER_FILE="+filename)
+
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("helper process failed: %w\n%s", err, out)
+			}
+		})
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	doc, err := pkg.Load(context.Background(), filename)
+	if err != nil {
+		t.Fatalf("expected Load to succeed, got %v", err)
+	}
+
+	if got := doc.Get("COUNTER").Literal; got != strconv.Itoa(processes) {
+		t.Fatalf("expected COUNTER to be %d, got %s", processes, got)
+	}
+}
+
+// TestLockTimesOut holds the lock in another process for longer than [pkg.LockTimeout],
+// which must fail rather than wait forever.
+func TestLockTimesOut(t *testing.T) {
+	defer func(timeout time.Duration) { pkg.LockTimeout = timeout }(pkg.LockTimeout)
+
+	pkg.LockTimeout = 100 * time.Millisecond
+
+	filename := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(filename, []byte("COUNTER=0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+	cmd.Env = append(os.Environ(), "DOTTIE_LOCK_HELPER_FILE="+filename, "DOTTIE_LOCK_HELPER_HOLD=1s")
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer cmd.Wait()
+
+	// Wait for the helper to hold the lock
+	if !bufio.NewScanner(stdout).Scan() {
+		t.Fatal("expected the helper process to report it holds the lock")
+	}
+
+	if _, err := pkg.Lock(filename); !errors.Is(err, pkg.ErrLocked) {
+		t.Fatalf("expected Lock to fail with ErrLocked, got %v", err)
+	}
+}
+
+func TestLockHelperProcess(t *testing.T) {
+	filename := os.Getenv("DOTTIE_LOCK_HELPER_FILE")
+	if filename == "" {
+		t.Skip("only used as a helper process by the lock tests")
+	}
+
+	ctx := context.Background()
+
+	unlock, err := pkg.Lock(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	if hold, err := time.ParseDuration(os.Getenv("DOTTIE_LOCK_HELPER_HOLD")); err == nil {
+		os.Stdout.WriteString("locked\n")
+		time.Sleep(hold)
+	}
+
+	doc, err := pkg.Load(ctx, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counter, err := strconv.Atoi(doc.Get("COUNTER").Literal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc.Get("COUNTER").SetLiteral(ctx, strconv.Itoa(counter+1))
+
+	if err := pkg.Save(ctx, filename, doc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseLargeInputRequiresLimitsFromContext(t *testing.T) {
+	t.Parallel()
+
+	var builder strings.Builder
+
+	for i := range 10_000 {
+		fmt.Fprintf(&builder, "KEY_%d=%q\n", i, strings.Repeat("v", 20))
+	}
+
+	input := builder.String()
+
+	if _, err := pkg.Parse(context.Background(), strings.NewReader(input), "large.env"); err == nil {
+		t.Fatal("expected Parse to fail with the default limits")
+	}
+
+	ctx := pkg.WithPreserveFormatting(scanner.ContextWithLimits(context.Background(), scanner.Unlimited), true)
+
+	doc, err := pkg.Parse(ctx, strings.NewReader(input), "large.env")
+	if err != nil {
+		t.Fatalf("expected Parse to succeed without limits, got %v", err)
+	}
+
+	if len(doc.AllAssignments()) != 10_000 {
+		t.Fatalf("expected 10000 assignments, got %d", len(doc.AllAssignments()))
+	}
+
+	if source, ok := doc.Get("KEY_9999").Source(); !ok || source != `KEY_9999="vvvvvvvvvvvvvvvvvvvv"` {
+		t.Fatalf("expected the original source of KEY_9999 to be kept, got %q", source)
+	}
+}
+
+// onlyReader hides the [io.Seeker] of the reader it wraps
+type onlyReader struct {
+	io.Reader
+}
+
+func TestParseKeepsSourceOnlyForPreserveFormatting(t *testing.T) {
+	t.Parallel()
+
+	const input = "A=1   # comment\n"
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		input    io.Reader
+		expected bool
+	}{
+		{name: "disabled", ctx: context.Background(), input: onlyReader{strings.NewReader(input)}, expected: false},
+		{name: "context", ctx: pkg.WithPreserveFormatting(context.Background(), true), input: onlyReader{strings.NewReader(input)}, expected: true},
+		{name: "annotation", ctx: context.Background(), input: strings.NewReader("# @dottie/preserve-formatting\n\n" + input), expected: true},
+		{name: "annotation without seeker", ctx: context.Background(), input: onlyReader{strings.NewReader("# @dottie/preserve-formatting\n\n" + input)}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			doc, err := pkg.Parse(tt.ctx, tt.input, "test.env")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if source, ok := doc.Get("A").Source(); ok != tt.expected || (ok && source != "A=1   # comment") {
+				t.Fatalf("expected the source to be kept: %v, got (%q, %v)", tt.expected, source, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+//go:build unix
+
+package pkg
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes an exclusive lock on [file] without waiting, reporting if it's held by someone else
+func tryLockFile(file *os.File) (bool, error) {
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+
+		switch {
+		case err == nil:
+			return true, nil
+
+		case errors.Is(err, syscall.EWOULDBLOCK):
+			return false, nil
+
+		case !errors.Is(err, syscall.EINTR):
+			return false, err
+		}
+	}
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
+
+// copyOwner gives [file] the same owner and group as [original]. Failing to do so
+// because we aren't allowed to (e.g. not running as root) is not an error.
+func copyOwner(file *os.File, original os.FileInfo) error {
+	stat, ok := original.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	err := file.Chown(int(stat.Uid), int(stat.Gid))
+	if errors.Is(err, syscall.EPERM) {
+		return nil
+	}
+
+	return err
+}
+
+// syncDir flushes the directory entry of a renamed file to disk
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
+
+// isReplaceUnsupported reports if the file can't be replaced by a rename, for example
+// when it's a bind-mounted file inside a container (EBUSY) or the temporary file ended
+// up on another file system (EXDEV).
+func isReplaceUnsupported(err error) bool {
+	return errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.EXDEV)
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package pkg
+
+import (
+	"errors"
+	"math"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// tryLockFile takes an exclusive lock on [file] without waiting, reporting if it's held by someone else
+func tryLockFile(file *os.File) (bool, error) {
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, math.MaxUint32, math.MaxUint32, &windows.Overlapped{})
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func unlockFile(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, math.MaxUint32, math.MaxUint32, &windows.Overlapped{})
+}
+
+// copyOwner is a no-op on Windows, where the replaced file inherits the ACL of the directory
+func copyOwner(*os.File, os.FileInfo) error {
+	return nil
+}
+
+// syncDir is a no-op on Windows, where directories can't be opened for syncing
+func syncDir(string) error {
+	return nil
+}
+
+func isReplaceUnsupported(error) bool {
+	return false
+}
@@ -0,0 +1,255 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/parser"
+)
+
+// Layers configures the files [LoadLayered] merges on top of the main file
+type Layers struct {
+	Mode  string   // Load the [LayerFiles] of the main file for this mode (e.g. "production")
+	Files []string // Additional files to merge on top, in order
+}
+
+// ContextWithLayers returns a context where [LoadLayered] merges the [layers] on top of the main file
+func ContextWithLayers(ctx context.Context, layers Layers) context.Context {
+	return context.WithValue(ctx, layersKey, layers)
+}
+
+// LayersFromContext returns the [Layers] configured with [ContextWithLayers], if any
+func LayersFromContext(ctx context.Context) (Layers, bool) {
+	layers, ok := ctx.Value(layersKey).(Layers)
+
+	return layers, ok
+}
+
+// LayerFiles returns the files loaded for [filename] in [mode], in the order they override each other,
+// like Vite and Next.js do (e.g. ".env", ".env.local", ".env.production" and ".env.production.local")
+func LayerFiles(filename, mode string) []string {
+	files := []string{filename, filename + ".local"}
+
+	if len(mode) > 0 {
+		files = append(files, filename+"."+mode, filename+"."+mode+".local")
+	}
+
+	return files
+}
+
+// Provenance records which of the layered files assigned each KEY
+type Provenance struct {
+	Files []string            // The files that were loaded, in the order they override each other
+	Keys  map[string][]string // The files assigning each KEY, in override order (the last one won)
+}
+
+// Layered reports if more than one file was loaded
+func (p *Provenance) Layered() bool {
+	return len(p.Files) > 1
+}
+
+// Winner returns the file the KEY [name] got its value from
+func (p *Provenance) Winner(name string) string {
+	files := p.Keys[name]
+	if len(files) == 0 {
+		return ""
+	}
+
+	return files[len(files)-1]
+}
+
+func (p *Provenance) record(name, filename string) {
+	p.Keys[name] = append(p.Keys[name], filename)
+}
+
+// LoadLayered loads [filename] and merges the [Layers] configured with [ContextWithLayers] on top of it.
+//
+// Files for the [Layers.Mode] are optional, while [Layers.Files] must exist. Keys in later files override
+// the value of the same KEY in earlier files, except disabled keys, which never override an existing KEY.
+//
+// Without [Layers] in the context, only [filename] is loaded, just like [Load].
+func LoadLayered(ctx context.Context, filename string, options ...parser.Option) (*ast.Document, *Provenance, error) {
+	files := []string{filename}
+	optional := map[string]bool{}
+
+	if layers, ok := LayersFromContext(ctx); ok {
+		if len(layers.Mode) > 0 {
+			files = LayerFiles(filename, layers.Mode)
+
+			for _, file := range files {
+				optional[file] = true
+			}
+		}
+
+		files = append(files, layers.Files...)
+	}
+
+	var (
+		merged       *ast.Document
+		syntaxErrors parser.Diagnostics
+		provenance   = &Provenance{Keys: map[string][]string{}}
+	)
+
+	for _, file := range files {
+		document, err := Load(ctx, file, options...)
+
+		var diagnostics parser.Diagnostics
+
+		switch {
+		case errors.Is(err, os.ErrNotExist) && optional[file]:
+			continue
+
+		// With [parser.WithErrorRecovery], the syntax errors of all files are returned together
+		case errors.As(err, &diagnostics) && document != nil:
+			syntaxErrors = append(syntaxErrors, diagnostics...)
+
+		case err != nil:
+			return nil, nil, err
+		}
+
+		provenance.Files = append(provenance.Files, file)
+
+		if merged == nil {
+			merged = document
+
+			for _, assignment := range merged.AllAssignments() {
+				provenance.record(assignment.Name, file)
+			}
+
+			continue
+		}
+
+		if err := mergeLayer(merged, document, file, provenance); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if merged == nil {
+		return nil, nil, fmt.Errorf("none of the files [ %v ] exist: %w", files, os.ErrNotExist)
+	}
+
+	// Compute the dependencies across all the layers, now that every KEY they may reference is merged
+	if provenance.Layered() {
+		merged.Initialize(ctx)
+
+		hoistLayerReferences(ctx, merged, provenance)
+	}
+
+	if len(syntaxErrors) > 0 {
+		return merged, provenance, syntaxErrors
+	}
+
+	return merged, provenance, nil
+}
+
+// mergeLayer merges the assignments of the [layer] document into the [merged] document.
+//
+// The assignments are merged as they are, keeping the [ast.Position] in the file they were read from. They are not
+// interpolated while merging, since they may reference KEYs that are only assigned in a later layer.
+func mergeLayer(merged, layer *ast.Document, filename string, provenance *Provenance) error {
+	for _, assignment := range layer.AllAssignments() {
+		existing := merged.Get(assignment.Name)
+
+		switch {
+		case existing == nil:
+			if assignment.Group != nil {
+				group := merged.EnsureGroup(assignment.Group.String())
+				group.Statements = append(group.Statements, assignment)
+
+				assignment.Group = group
+			} else {
+				merged.Statements = append(merged.Statements, assignment)
+			}
+
+			merged.ReindexStatements()
+
+		case existing.Enabled && !assignment.Enabled:
+			continue
+
+		default:
+			// Keep the documentation of the KEY, unless the layer has its own
+			if len(assignment.Comments) == 0 {
+				assignment.Comments = existing.Comments
+			}
+
+			assignment.Group = existing.Group
+
+			if err := merged.Replace(assignment); err != nil {
+				return fmt.Errorf("could not merge [ %s ] from [ %s ]: %w", assignment.Name, filename, err)
+			}
+		}
+
+		provenance.record(assignment.Name, filename)
+	}
+
+	return nil
+}
+
+// hoistLayerReferences moves KEYs from a later layer in front of the assignments from an earlier layer referencing them.
+//
+// Interpolation only resolves KEYs assigned before the assignment being interpolated, like a shell does, but a file
+// may reference KEYs it expects a later layer to provide (e.g. a ".env.local" using a KEY from ".env.production").
+func hoistLayerReferences(ctx context.Context, merged *ast.Document, provenance *Provenance) {
+	layer := map[string]int{}
+	for i, file := range provenance.Files {
+		layer[file] = i
+	}
+
+	for moved := true; moved; {
+		moved = false
+
+		for _, assignment := range merged.AllAssignments() {
+			for _, name := range slices.Sorted(maps.Keys(assignment.Dependencies)) {
+				dependency := merged.Get(name)
+				if dependency == nil || dependency.Position.Index < assignment.Position.Index {
+					continue
+				}
+
+				if layer[provenance.Winner(dependency.Name)] <= layer[provenance.Winner(assignment.Name)] {
+					continue
+				}
+
+				moveBefore(merged, dependency, assignment)
+				merged.Initialize(ctx)
+
+				moved = true
+
+				break
+			}
+
+			if moved {
+				break
+			}
+		}
+	}
+}
+
+// moveBefore moves the [assignment] right in front of the [target] assignment, into the same group
+func moveBefore(doc *ast.Document, assignment, target *ast.Assignment) {
+	remove := func(statement ast.Statement) bool { return statement == assignment }
+
+	if assignment.Group != nil {
+		assignment.Group.Statements = slices.DeleteFunc(assignment.Group.Statements, remove)
+	} else {
+		doc.Statements = slices.DeleteFunc(doc.Statements, remove)
+	}
+
+	insert := func(statements []ast.Statement) []ast.Statement {
+		index := slices.IndexFunc(statements, func(statement ast.Statement) bool { return statement == target })
+
+		return slices.Insert(statements, index, ast.Statement(assignment))
+	}
+
+	assignment.Group = target.Group
+
+	if target.Group != nil {
+		target.Group.Statements = insert(target.Group.Statements)
+	} else {
+		doc.Statements = insert(doc.Statements)
+	}
+}
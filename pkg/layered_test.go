@@ -0,0 +1,142 @@
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	pkg "github.com/jippi/dottie/pkg"
+)
+
+func writeLayer(t *testing.T, filename, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLayeredMergesFilesInOverrideOrder(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, ".env")
+	extra := filepath.Join(dir, "extra.env")
+
+	writeLayer(t, filename, "A=base\nB=base\nC=base\nD=base\n")
+	writeLayer(t, filename+".local", "A=local\n#B=disabled\n")
+	writeLayer(t, filename+".production", "C=production\nNEW=production\n")
+	writeLayer(t, extra, "D=extra\n")
+
+	ctx := pkg.ContextWithLayers(context.Background(), pkg.Layers{Mode: "production", Files: []string{extra}})
+
+	doc, provenance, err := pkg.LoadLayered(ctx, filename)
+	if err != nil {
+		t.Fatalf("expected LoadLayered to succeed, got %v", err)
+	}
+
+	expected := map[string]string{"A": "local", "B": "base", "C": "production", "D": "extra", "NEW": "production"}
+
+	for name, value := range expected {
+		assignment := doc.Get(name)
+		if assignment == nil || !assignment.Enabled || assignment.Literal != value {
+			t.Fatalf("expected [%s] to be [%s], got %+v", name, value, assignment)
+		}
+	}
+
+	// .env.production.local doesn't exist, and is skipped
+	if files := []string{filename, filename + ".local", filename + ".production", extra}; !slices.Equal(provenance.Files, files) {
+		t.Fatalf("expected files %v, got %v", files, provenance.Files)
+	}
+
+	if winner := provenance.Winner("A"); winner != filename+".local" {
+		t.Fatalf("expected [A] to come from [%s.local], got [%s]", filename, winner)
+	}
+
+	if winner := provenance.Winner("B"); winner != filename {
+		t.Fatalf("expected [B] to come from [%s], got [%s]", filename, winner)
+	}
+
+	if files := provenance.Keys["C"]; !slices.Equal(files, []string{filename, filename + ".production"}) {
+		t.Fatalf("expected [C] to be assigned in the base and production files, got %v", files)
+	}
+}
+
+// TestLoadLayeredResolvesReferencesAcrossLayers references a KEY that is only assigned in a later layer,
+// which can only be resolved once all the layers are merged.
+func TestLoadLayeredResolvesReferencesAcrossLayers(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), ".env")
+
+	writeLayer(t, filename, "HOST=localhost\n")
+	writeLayer(t, filename+".local", "URL=\"https://${HOST}/${LATER}\"\n")
+	writeLayer(t, filename+".production", "HOST=example.com\nLATER=x\n")
+
+	ctx := pkg.ContextWithLayers(context.Background(), pkg.Layers{Mode: "production"})
+
+	doc, _, err := pkg.LoadLayered(ctx, filename)
+	if err != nil {
+		t.Fatalf("expected LoadLayered to succeed, got %v", err)
+	}
+
+	if err := doc.InterpolateAll(ctx); err != nil {
+		t.Fatalf("expected InterpolateAll to succeed, got %v", err)
+	}
+
+	url := doc.Get("URL")
+	if url.Interpolated != "https://example.com/x" {
+		t.Fatalf("expected [URL] to use the values of the last layers, got [%s]", url.Interpolated)
+	}
+
+	if filepath.Base(url.Position.File) != ".env.local" || url.Position.Line != 1 {
+		t.Fatalf("expected [URL] to keep its position in [.env.local], got %s", url.Position)
+	}
+
+	if _, ok := url.Dependencies["LATER"]; !ok {
+		t.Fatal("expected [URL] to depend on [LATER]")
+	}
+}
+
+func TestLoadLayeredWithoutLayersLoadsOneFile(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), ".env")
+
+	writeLayer(t, filename, "A=base\n")
+	writeLayer(t, filename+".local", "A=local\n")
+
+	doc, provenance, err := pkg.LoadLayered(context.Background(), filename)
+	if err != nil {
+		t.Fatalf("expected LoadLayered to succeed, got %v", err)
+	}
+
+	if doc.Get("A").Literal != "base" || provenance.Layered() {
+		t.Fatal("expected only the main file to be loaded")
+	}
+}
+
+func TestLoadLayeredRequiresExplicitLayers(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, ".env")
+
+	writeLayer(t, filename, "A=base\n")
+
+	ctx := pkg.ContextWithLayers(context.Background(), pkg.Layers{Files: []string{filepath.Join(dir, "missing.env")}})
+
+	if _, _, err := pkg.LoadLayered(ctx, filename); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a missing layer to fail, got %v", err)
+	}
+
+	// Files for a mode are optional, but at least one of them must exist
+	ctx = pkg.ContextWithLayers(context.Background(), pkg.Layers{Mode: "production"})
+
+	if _, _, err := pkg.LoadLayered(ctx, filepath.Join(dir, "missing.env")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no existing files to fail, got %v", err)
+	}
+}
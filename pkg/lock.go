@@ -0,0 +1,169 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LockTimeout is how long [Lock] waits for another dottie process to release the lock
+var LockTimeout = 10 * time.Second
+
+// ErrLocked is returned by [Lock] when another dottie process holds the lock for longer than [LockTimeout]
+var ErrLocked = errors.New("file is locked by another dottie process")
+
+// heldLocks tracks the files locked by this process, so [Save] can be called while
+// a command is holding the lock from [Lock] without deadlocking on itself.
+var (
+	heldLocks   = map[string]*fileLock{}
+	heldLocksMu sync.Mutex
+)
+
+type fileLock struct {
+	file  *os.File
+	count int
+}
+
+// Lock takes an exclusive advisory lock for [filename], waiting up to [LockTimeout] for any other
+// dottie process holding it to be done. Commands that load, change and save a file should hold the
+// lock for the whole duration, so concurrent runs can't lose each others writes. The lock should not
+// be held while running user provided commands, since they might run dottie on the same file.
+//
+// The lock is taken on a "<filename>.lock" file next to [filename], which is removed
+// again when the returned unlock function is called.
+func Lock(filename string) (func(), error) {
+	path, err := lockPath(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	heldLocksMu.Lock()
+	defer heldLocksMu.Unlock()
+
+	if held, ok := heldLocks[path]; ok {
+		held.count++
+
+		return func() { unlock(path) }, nil
+	}
+
+	file, err := acquireLock(path)
+	if err != nil {
+		// Without write access to the directory there can't be a lock file either, but
+		// we might still be allowed to change the file itself
+		if os.IsPermission(err) {
+			return func() {}, nil
+		}
+
+		return nil, fmt.Errorf("could not lock [ %s ]: %w", filename, err)
+	}
+
+	heldLocks[path] = &fileLock{file: file, count: 1}
+
+	return func() { unlock(path) }, nil
+}
+
+func unlock(path string) {
+	heldLocksMu.Lock()
+	defer heldLocksMu.Unlock()
+
+	held, ok := heldLocks[path]
+	if !ok {
+		return
+	}
+
+	held.count--
+	if held.count > 0 {
+		return
+	}
+
+	delete(heldLocks, path)
+
+	// Remove the lock file *before* releasing the lock, processes waiting on the
+	// now unlinked file will notice and retry on a fresh one (see [acquireLock])
+	os.Remove(path)
+	unlockFile(held.file)
+	held.file.Close()
+}
+
+// acquireLock opens and locks the lock file at [path].
+//
+// Since the lock file is removed on unlock, the file we waited for might have been unlinked
+// by the time we get the lock; in that case we start over with the new file.
+func acquireLock(path string) (*os.File, error) {
+	deadline := time.Now().Add(LockTimeout)
+
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, err
+		}
+
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+
+			return nil, err
+		}
+
+		if !locked {
+			file.Close()
+
+			if time.Now().After(deadline) {
+				return nil, ErrLocked
+			}
+
+			time.Sleep(50 * time.Millisecond)
+
+			continue
+		}
+
+		info, err := file.Stat()
+		if err != nil {
+			unlockFile(file)
+			file.Close()
+
+			return nil, err
+		}
+
+		current, err := os.Stat(path)
+		if err == nil && os.SameFile(info, current) {
+			return file, nil
+		}
+
+		unlockFile(file)
+		file.Close()
+	}
+}
+
+func lockPath(filename string) (string, error) {
+	path, err := resolvePath(filename)
+	if err != nil {
+		return "", err
+	}
+
+	return path + ".lock", nil
+}
+
+// resolvePath returns the absolute path of [filename] with symlinks resolved, so
+// saving through a symlink replaces the target rather than the link itself.
+func resolvePath(filename string) (string, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	switch {
+	case err == nil:
+		return resolved, nil
+
+	case os.IsNotExist(err):
+		return path, nil
+
+	default:
+		return "", err
+	}
+}
@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diagnostic describes a single syntax problem found while parsing.
+type Diagnostic struct {
+	File    string `json:"file"`
+	Line    uint   `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// Diagnostics is the list of all syntax problems found while parsing with [WithErrorRecovery].
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	messages := make([]string, 0, len(d))
+
+	for _, diagnostic := range d {
+		messages = append(messages, diagnostic.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
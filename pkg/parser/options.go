@@ -0,0 +1,14 @@
+package parser
+
+type Option func(*Parser)
+
+// WithErrorRecovery makes the parser skip to the next line when it encounters a
+// syntax error, instead of stopping.
+//
+// [Parser.Parse] will then return the (partial) document, together with a
+// [Diagnostics] error listing all the syntax problems found.
+func WithErrorRecovery(enabled bool) Option {
+	return func(p *Parser) {
+		p.recover = enabled
+	}
+}
@@ -2,11 +2,14 @@ package parser
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jippi/dottie/pkg/ast"
 	"github.com/jippi/dottie/pkg/token"
@@ -24,10 +27,12 @@ type Parser struct {
 	scanner       Scanner
 	token         token.Token
 	previousToken token.Token
+	recover       bool
+	diagnostics   Diagnostics
 }
 
 // New returns new Parser.
-func New(ctx context.Context, scanner Scanner, filename string) *Parser {
+func New(ctx context.Context, scanner Scanner, filename string, options ...Option) *Parser {
 	// If we're under test, the filename will be completely random, which will
 	// mess with golden file output, so we override the filename to a static
 	// value to make the tests deterministic.
@@ -43,11 +48,17 @@ func New(ctx context.Context, scanner Scanner, filename string) *Parser {
 		),
 	)
 
-	return &Parser{
+	parser := &Parser{
 		filename: filename,
 		scanner:  scanner,
 		token:    scanner.NextToken(ctx),
 	}
+
+	for _, option := range options {
+		option(parser)
+	}
+
+	return parser
 }
 
 // Parse parses the .env file and returns an ast.Statement.
@@ -77,7 +88,13 @@ func (p *Parser) Parse(ctx context.Context) (document *ast.Document, err error)
 
 		stmt, err := p.parseStatement(ctx)
 		if err != nil {
-			return nil, err
+			if !p.recover {
+				return nil, err
+			}
+
+			p.recoverFromError(ctx, err)
+
+			continue
 		}
 
 		slogctx.Debug(ctx, "Parser.Parse() processing statement", slog.Any("statement", stmt))
@@ -114,8 +131,6 @@ func (p *Parser) Parse(ctx context.Context) (document *ast.Document, err error)
 				val.Position.FirstLine = val.Position.Line
 			}
 
-			val.Position.LastLine = val.Position.Line
-
 			// Assign the assignment to a grouping if such exists
 			if currentGroup != nil {
 				val.Group = currentGroup
@@ -203,9 +218,34 @@ func (p *Parser) Parse(ctx context.Context) (document *ast.Document, err error)
 	// computed immediately
 	document.Initialize(ctx)
 
+	if len(p.diagnostics) > 0 {
+		return document, p.diagnostics
+	}
+
 	return document, nil
 }
 
+// recoverFromError records the syntax error and skips past the rest of the
+// current line, so parsing can continue on the next one.
+func (p *Parser) recoverFromError(ctx context.Context, err error) {
+	slogctx.Debug(ctx, "Parser.recoverFromError()", slog.Any("error", err))
+
+	var diagnostic Diagnostic
+	if !errors.As(err, &diagnostic) {
+		diagnostic = p.newDiagnostic(err.Error())
+	}
+
+	p.diagnostics = append(p.diagnostics, diagnostic)
+
+	for p.token.Type != token.NewLine && p.token.Type != token.EOF {
+		p.nextToken(ctx)
+	}
+
+	if p.token.Type == token.NewLine {
+		p.nextToken(ctx)
+	}
+}
+
 func (p *Parser) parseStatement(ctx context.Context) (ast.Statement, error) {
 	slogctx.Debug(ctx, "Parser.parseStatement()")
 
@@ -237,7 +277,7 @@ func (p *Parser) parseStatement(ctx context.Context) (ast.Statement, error) {
 		return res, nil
 
 	default:
-		return nil, fmt.Errorf("(B) unexpected statement: %s(%q)", p.token.Type, p.token.Literal)
+		return nil, p.newDiagnostic(fmt.Sprintf("(B) unexpected statement: %s(%q)", p.token.Type, p.token.Literal))
 	}
 }
 
@@ -246,6 +286,8 @@ func (p *Parser) parseGroupStatement(ctx context.Context) (ast.Statement, error)
 
 	var group *ast.Group
 
+	banner := p.token
+
 	p.nextToken(ctx)
 	p.skipBlankLine(ctx)
 
@@ -257,6 +299,10 @@ func (p *Parser) parseGroupStatement(ctx context.Context) (ast.Statement, error)
 				FirstLine: p.token.LineNumber,
 				Line:      p.token.LineNumber,
 				LastLine:  p.token.LineNumber,
+				Column:    p.token.Column,
+				EndColumn: p.token.EndColumn,
+				Offset:    p.token.StartOffset,
+				EndOffset: p.token.EndOffset,
 			},
 		}
 
@@ -269,6 +315,15 @@ func (p *Parser) parseGroupStatement(ctx context.Context) (ast.Statement, error)
 
 	switch p.token.Type {
 	case token.GroupBanner:
+		group.HeaderSpan = ast.Span{
+			Line:      banner.LineNumber,
+			Column:    banner.Column,
+			EndLine:   p.token.LineNumber,
+			EndColumn: p.token.EndColumn,
+			Offset:    banner.StartOffset,
+			EndOffset: p.token.EndOffset,
+		}
+
 		p.nextToken(ctx)
 
 		return group, nil
@@ -288,6 +343,10 @@ func (p *Parser) parseCommentStatement(ctx context.Context) (ast.Statement, erro
 			Line:      p.token.LineNumber,
 			FirstLine: p.token.LineNumber,
 			LastLine:  p.token.LineNumber,
+			Column:    p.token.Column,
+			EndColumn: p.token.EndColumn,
+			Offset:    p.token.StartOffset,
+			EndOffset: p.token.EndOffset,
 		},
 	}
 
@@ -304,24 +363,25 @@ func (p *Parser) parseRowStatement(ctx context.Context) (ast.Statement, error) {
 		stmt *ast.Assignment
 	)
 
-	name := p.token.Literal
-	active := !p.token.Commented
+	identifier := p.token
 
 	p.nextToken(ctx)
 
 	switch p.token.Type {
 	case token.NewLine, token.EOF:
-		stmt = p.parseNakedAssign(ctx, name)
+		stmt = p.parseNakedAssign(ctx, identifier, nil)
 
 	case token.Assign:
+		assign := p.token
+
 		p.nextToken(ctx)
 
 		switch p.token.Type {
-		case token.NewLine, token.EOF:
-			stmt = p.parseNakedAssign(ctx, name)
+		case token.NewLine, token.EOF, token.InlineComment:
+			stmt = p.parseNakedAssign(ctx, identifier, &assign)
 
 		case token.Value, token.RawValue:
-			stmt, err = p.parseCompleteAssign(ctx, name)
+			stmt, err = p.parseCompleteAssign(ctx, identifier, assign)
 
 		default:
 			_, err = p.unexpectedToken("parseRowStatement 1")
@@ -336,7 +396,8 @@ func (p *Parser) parseRowStatement(ctx context.Context) (ast.Statement, error) {
 	}
 
 	if stmt != nil {
-		stmt.Enabled = active
+		stmt.Enabled = !identifier.Commented
+		stmt.Exported = identifier.Exported
 
 		return stmt, err
 	}
@@ -344,46 +405,39 @@ func (p *Parser) parseRowStatement(ctx context.Context) (ast.Statement, error) {
 	return p.unexpectedToken("parseRowStatement 3")
 }
 
-func (p *Parser) parseNakedAssign(ctx context.Context, name string) *ast.Assignment {
+// parseNakedAssign parses an assignment without a value, either "KEY" or "KEY=" (if [assign] is provided).
+func (p *Parser) parseNakedAssign(ctx context.Context, identifier token.Token, assign *token.Token) *ast.Assignment {
 	slogctx.Debug(ctx, "Parser.parseNakedAssign()")
 
+	assignment := newAssignment(identifier, assign, nil)
+
+	p.parseInlineComment(ctx, assignment)
+
 	defer p.nextToken(ctx)
 
-	return &ast.Assignment{
-		Name:    name,
-		Enabled: p.token.Commented,
-		Quote:   token.NoQuote,
-		Position: ast.Position{
-			FirstLine: p.token.LineNumber,
-			Line:      p.token.LineNumber,
-			LastLine:  p.token.LineNumber,
-		},
-	}
+	return assignment
 }
 
-func (p *Parser) parseCompleteAssign(ctx context.Context, name string) (*ast.Assignment, error) {
+// parseCompleteAssign parses the value of an assignment.
+//
+// Quoted values may span multiple lines, in which case [ast.Position.LastLine]
+// will point to the line with the closing quote.
+func (p *Parser) parseCompleteAssign(ctx context.Context, identifier, assign token.Token) (*ast.Assignment, error) {
 	slogctx.Debug(ctx, "Parser.parseCompleteAssign()")
 
-	assignment := p.token
+	value := p.token
 
 	p.nextToken(ctx)
 
+	assignment := newAssignment(identifier, &assign, &value)
+
+	p.parseInlineComment(ctx, assignment)
+
 	switch p.token.Type {
 	case token.NewLine, token.EOF:
 		defer p.nextToken(ctx)
 
-		return &ast.Assignment{
-			Name:     name,
-			Literal:  assignment.Literal,
-			Complete: true,
-			Enabled:  p.token.Commented,
-			Quote:    assignment.Quote,
-			Position: ast.Position{
-				FirstLine: p.token.LineNumber,
-				Line:      p.token.LineNumber,
-				LastLine:  p.token.LineNumber,
-			},
-		}, nil
+		return assignment, nil
 
 	default:
 		_, err := p.unexpectedToken("parseCompleteAssign 1")
@@ -392,6 +446,24 @@ func (p *Parser) parseCompleteAssign(ctx context.Context, name string) (*ast.Ass
 	}
 }
 
+// parseInlineComment consumes the (optional) trailing comment of an assignment
+// line and attaches it to the [assignment].
+func (p *Parser) parseInlineComment(ctx context.Context, assignment *ast.Assignment) {
+	if p.token.Type != token.InlineComment {
+		return
+	}
+
+	slogctx.Debug(ctx, "Parser.parseInlineComment()")
+
+	comment := strings.TrimRightFunc(p.token.Literal, unicode.IsSpace)
+
+	assignment.InlineComment = comment
+	assignment.Position.EndColumn = p.token.Column + utf8.RuneCountInString(comment)
+	assignment.Position.EndOffset = p.token.StartOffset + len(comment)
+
+	p.nextToken(ctx)
+}
+
 func (p *Parser) nextToken(ctx context.Context) {
 	p.previousToken = p.token
 	p.token = p.scanner.NextToken(ctx)
@@ -414,5 +486,14 @@ func (p *Parser) skipBlankLine(ctx context.Context) {
 }
 
 func (p *Parser) unexpectedToken(details string) (ast.Statement, error) {
-	return nil, fmt.Errorf("unexpected token at line %d: %s(%s) - %s", p.token.LineNumber, p.token.Type, p.token.Literal, details)
+	return nil, p.newDiagnostic(fmt.Sprintf("unexpected token %s(%s) - %s", p.token.Type, p.token.Literal, details))
+}
+
+func (p *Parser) newDiagnostic(message string) Diagnostic {
+	return Diagnostic{
+		File:    p.filename,
+		Line:    p.token.LineNumber,
+		Column:  p.token.Column,
+		Message: message,
+	}
 }
@@ -36,6 +36,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 11,
+								Offset:    0,
+								EndOffset: 10,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    0,
+								EndOffset: 4,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 6,
+								Offset:    4,
+								EndOffset: 5,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    6,
+								EndLine:   1,
+								EndColumn: 11,
+								Offset:    5,
+								EndOffset: 10,
 							},
 							Complete: true,
 							Enabled:  true,
@@ -58,6 +86,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 13,
+								Offset:    0,
+								EndOffset: 12,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    0,
+								EndOffset: 4,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 6,
+								Offset:    4,
+								EndOffset: 5,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    6,
+								EndLine:   1,
+								EndColumn: 13,
+								Offset:    5,
+								EndOffset: 12,
 							},
 							Complete: true,
 							Enabled:  true,
@@ -80,6 +136,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 13,
+								Offset:    0,
+								EndOffset: 12,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    0,
+								EndOffset: 4,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 6,
+								Offset:    4,
+								EndOffset: 5,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    6,
+								EndLine:   1,
+								EndColumn: 13,
+								Offset:    5,
+								EndOffset: 12,
 							},
 							Quote:    token.SingleQuote,
 							Complete: true,
@@ -103,6 +187,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 6,
+								Offset:    0,
+								EndOffset: 5,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    0,
+								EndOffset: 4,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 6,
+								Offset:    4,
+								EndOffset: 5,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    6,
+								EndLine:   1,
+								EndColumn: 6,
+								Offset:    5,
+								EndOffset: 5,
 							},
 							Quote: token.NoQuote,
 						},
@@ -123,6 +235,18 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 5,
+								Offset:    0,
+								EndOffset: 4,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    0,
+								EndOffset: 4,
 							},
 							Quote:   token.NoQuote,
 							Enabled: true,
@@ -154,6 +278,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      5,
 								FirstLine: 5,
 								LastLine:  5,
+								Column:    1,
+								EndColumn: 6,
+								Offset:    4,
+								EndOffset: 9,
+							},
+							NameSpan: ast.Span{
+								Line:      5,
+								Column:    1,
+								EndLine:   5,
+								EndColumn: 5,
+								Offset:    4,
+								EndOffset: 8,
+							},
+							AssignSpan: ast.Span{
+								Line:      5,
+								Column:    5,
+								EndLine:   5,
+								EndColumn: 6,
+								Offset:    8,
+								EndOffset: 9,
+							},
+							ValueSpan: ast.Span{
+								Line:      5,
+								Column:    6,
+								EndLine:   5,
+								EndColumn: 6,
+								Offset:    9,
+								EndOffset: 9,
 							},
 							Complete: false,
 							Enabled:  true,
@@ -190,6 +342,34 @@ func TestParser_Parse(t *testing.T) {
 								FirstLine: 1,
 								LastLine:  1,
 								Index:     0,
+								Column:    1,
+								EndColumn: 22,
+								Offset:    0,
+								EndOffset: 21,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 16,
+								Offset:    0,
+								EndOffset: 15,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    16,
+								EndLine:   1,
+								EndColumn: 17,
+								Offset:    15,
+								EndOffset: 16,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    17,
+								EndLine:   1,
+								EndColumn: 22,
+								Offset:    16,
+								EndOffset: 21,
 							},
 						},
 						&ast.Assignment{
@@ -205,6 +385,34 @@ func TestParser_Parse(t *testing.T) {
 								FirstLine: 2,
 								LastLine:  2,
 								Index:     1,
+								Column:    1,
+								EndColumn: 27,
+								Offset:    22,
+								EndOffset: 48,
+							},
+							NameSpan: ast.Span{
+								Line:      2,
+								Column:    1,
+								EndLine:   2,
+								EndColumn: 24,
+								Offset:    22,
+								EndOffset: 45,
+							},
+							AssignSpan: ast.Span{
+								Line:      2,
+								Column:    24,
+								EndLine:   2,
+								EndColumn: 25,
+								Offset:    45,
+								EndOffset: 46,
+							},
+							ValueSpan: ast.Span{
+								Line:      2,
+								Column:    25,
+								EndLine:   2,
+								EndColumn: 27,
+								Offset:    46,
+								EndOffset: 48,
 							},
 						},
 						&ast.Assignment{
@@ -220,6 +428,34 @@ func TestParser_Parse(t *testing.T) {
 								FirstLine: 3,
 								LastLine:  3,
 								Index:     2,
+								Column:    1,
+								EndColumn: 45,
+								Offset:    49,
+								EndOffset: 93,
+							},
+							NameSpan: ast.Span{
+								Line:      3,
+								Column:    1,
+								EndLine:   3,
+								EndColumn: 22,
+								Offset:    49,
+								EndOffset: 70,
+							},
+							AssignSpan: ast.Span{
+								Line:      3,
+								Column:    22,
+								EndLine:   3,
+								EndColumn: 23,
+								Offset:    70,
+								EndOffset: 71,
+							},
+							ValueSpan: ast.Span{
+								Line:      3,
+								Column:    23,
+								EndLine:   3,
+								EndColumn: 45,
+								Offset:    71,
+								EndOffset: 93,
 							},
 						},
 					},
@@ -239,6 +475,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      2,
 								FirstLine: 1,
 								LastLine:  2,
+								Column:    1,
+								EndColumn: 22,
+								Offset:    12,
+								EndOffset: 33,
+							},
+							NameSpan: ast.Span{
+								Line:      2,
+								Column:    1,
+								EndLine:   2,
+								EndColumn: 16,
+								Offset:    12,
+								EndOffset: 27,
+							},
+							AssignSpan: ast.Span{
+								Line:      2,
+								Column:    16,
+								EndLine:   2,
+								EndColumn: 17,
+								Offset:    27,
+								EndOffset: 28,
+							},
+							ValueSpan: ast.Span{
+								Line:      2,
+								Column:    17,
+								EndLine:   2,
+								EndColumn: 22,
+								Offset:    28,
+								EndOffset: 33,
 							},
 							Quote:    token.NoQuote,
 							Complete: true,
@@ -251,6 +515,10 @@ func TestParser_Parse(t *testing.T) {
 										Line:      1,
 										FirstLine: 1,
 										LastLine:  1,
+										Column:    1,
+										EndColumn: 12,
+										Offset:    0,
+										EndOffset: 11,
 									},
 								},
 							},
@@ -262,6 +530,10 @@ func TestParser_Parse(t *testing.T) {
 								Line:      3,
 								FirstLine: 3,
 								LastLine:  3,
+								Column:    1,
+								EndColumn: 12,
+								Offset:    34,
+								EndOffset: 45,
 							},
 						},
 					},
@@ -281,6 +553,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 15,
+								Offset:    0,
+								EndOffset: 14,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 15,
+								Offset:    4,
+								EndOffset: 14,
 							},
 							Complete: true,
 							Enabled:  true,
@@ -303,6 +603,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 13,
+								Offset:    0,
+								EndOffset: 12,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 13,
+								Offset:    4,
+								EndOffset: 12,
 							},
 							Quote:    token.NoQuote,
 							Complete: true,
@@ -325,6 +653,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 10,
+								Offset:    0,
+								EndOffset: 9,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 10,
+								Offset:    4,
+								EndOffset: 9,
 							},
 							Quote:    token.DoubleQuote,
 							Complete: true,
@@ -347,6 +703,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 12,
+								Offset:    0,
+								EndOffset: 11,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 12,
+								Offset:    4,
+								EndOffset: 11,
 							},
 							Quote:    token.NoQuote,
 							Complete: true,
@@ -356,19 +740,197 @@ func TestParser_Parse(t *testing.T) {
 				},
 			},
 			{
-				name:  `inline comments is a part of value`,
+				name:  `inline comment after naked value`,
 				input: `FOO=bar # this is foo`,
+				expected: &ast.Document{
+					Statements: []ast.Statement{
+						&ast.Assignment{
+							Name:          "FOO",
+							Literal:       "bar",
+							Interpolated:  "bar",
+							InlineComment: "# this is foo",
+							Position: ast.Position{
+								File:      "-",
+								Line:      1,
+								FirstLine: 1,
+								LastLine:  1,
+								Column:    1,
+								EndColumn: 22,
+								Offset:    0,
+								EndOffset: 21,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 8,
+								Offset:    4,
+								EndOffset: 7,
+							},
+							Complete: true,
+							Enabled:  true,
+							Quote:    token.NoQuote,
+						},
+					},
+				},
+			},
+			{
+				name:  `inline comment after double quoted value`,
+				input: "FOO=\"bar # baz\"\t#this is foo ",
+				expected: &ast.Document{
+					Statements: []ast.Statement{
+						&ast.Assignment{
+							Name:          "FOO",
+							Literal:       "bar # baz",
+							Interpolated:  "bar # baz",
+							InlineComment: "#this is foo",
+							Position: ast.Position{
+								File:      "-",
+								Line:      1,
+								FirstLine: 1,
+								LastLine:  1,
+								Column:    1,
+								EndColumn: 29,
+								Offset:    0,
+								EndOffset: 28,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 16,
+								Offset:    4,
+								EndOffset: 15,
+							},
+							Complete: true,
+							Enabled:  true,
+							Quote:    token.DoubleQuote,
+						},
+					},
+				},
+			},
+			{
+				name:  `inline comment after empty value`,
+				input: `FOO= # this is foo`,
+				expected: &ast.Document{
+					Statements: []ast.Statement{
+						&ast.Assignment{
+							Name:          "FOO",
+							InlineComment: "# this is foo",
+							Position: ast.Position{
+								File:      "-",
+								Line:      1,
+								FirstLine: 1,
+								LastLine:  1,
+								Column:    1,
+								EndColumn: 19,
+								Offset:    0,
+								EndOffset: 18,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    4,
+								EndOffset: 4,
+							},
+							Enabled: true,
+							Quote:   token.NoQuote,
+						},
+					},
+				},
+			},
+			{
+				name:  `hash without leading whitespace is a part of naked value`,
+				input: `FOO=bar#baz`,
 				expected: &ast.Document{
 					Statements: []ast.Statement{
 						&ast.Assignment{
 							Name:         "FOO",
-							Literal:      "bar # this is foo",
-							Interpolated: "bar # this is foo",
+							Literal:      "bar#baz",
+							Interpolated: "bar#baz",
 							Position: ast.Position{
 								File:      "-",
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 12,
+								Offset:    0,
+								EndOffset: 11,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 12,
+								Offset:    4,
+								EndOffset: 11,
 							},
 							Complete: true,
 							Enabled:  true,
@@ -391,6 +953,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 14,
+								Offset:    0,
+								EndOffset: 13,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 14,
+								Offset:    4,
+								EndOffset: 13,
 							},
 							Quote:    token.DoubleQuote,
 							Complete: true,
@@ -413,6 +1003,34 @@ func TestParser_Parse(t *testing.T) {
 								Line:      1,
 								FirstLine: 1,
 								LastLine:  1,
+								Column:    1,
+								EndColumn: 14,
+								Offset:    0,
+								EndOffset: 13,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   1,
+								EndColumn: 14,
+								Offset:    4,
+								EndOffset: 13,
 							},
 							Quote:    token.SingleQuote,
 							Complete: true,
@@ -421,7 +1039,165 @@ func TestParser_Parse(t *testing.T) {
 					},
 				},
 			},
-		}
+
+			{
+				name:  `multi-line double quoted value`,
+				input: "FOO=\"line 1\nline 2\nline 3\"\nBAR=baz",
+				expected: &ast.Document{
+					Statements: []ast.Statement{
+						&ast.Assignment{
+							Name:         "FOO",
+							Literal:      "line 1\nline 2\nline 3",
+							Interpolated: "line 1\nline 2\nline 3",
+							Position: ast.Position{
+								File:      "-",
+								Line:      1,
+								FirstLine: 1,
+								LastLine:  3,
+								Column:    1,
+								EndColumn: 8,
+								Offset:    0,
+								EndOffset: 26,
+							},
+							NameSpan: ast.Span{
+								Line:      1,
+								Column:    1,
+								EndLine:   1,
+								EndColumn: 4,
+								Offset:    0,
+								EndOffset: 3,
+							},
+							AssignSpan: ast.Span{
+								Line:      1,
+								Column:    4,
+								EndLine:   1,
+								EndColumn: 5,
+								Offset:    3,
+								EndOffset: 4,
+							},
+							ValueSpan: ast.Span{
+								Line:      1,
+								Column:    5,
+								EndLine:   3,
+								EndColumn: 8,
+								Offset:    4,
+								EndOffset: 26,
+							},
+							Quote:    token.DoubleQuote,
+							Complete: true,
+							Enabled:  true,
+						},
+						&ast.Assignment{
+							Name:         "BAR",
+							Literal:      "baz",
+							Interpolated: "baz",
+							Position: ast.Position{
+								File:      "-",
+								Line:      4,
+								FirstLine: 4,
+								LastLine:  4,
+								Index:     1,
+								Column:    1,
+								EndColumn: 8,
+								Offset:    27,
+								EndOffset: 34,
+							},
+							NameSpan: ast.Span{
+								Line:      4,
+								Column:    1,
+								EndLine:   4,
+								EndColumn: 4,
+								Offset:    27,
+								EndOffset: 30,
+							},
+							AssignSpan: ast.Span{
+								Line:      4,
+								Column:    4,
+								EndLine:   4,
+								EndColumn: 5,
+								Offset:    30,
+								EndOffset: 31,
+							},
+							ValueSpan: ast.Span{
+								Line:      4,
+								Column:    5,
+								EndLine:   4,
+								EndColumn: 8,
+								Offset:    31,
+								EndOffset: 34,
+							},
+							Quote:    token.NoQuote,
+							Complete: true,
+							Enabled:  true,
+						},
+					},
+				},
+			},
+			{
+				name:  `multi-line single quoted value with comment`,
+				input: "# comment\nFOO='{\n  \"a\": 1\n}'",
+				expected: &ast.Document{
+					Statements: []ast.Statement{
+						&ast.Assignment{
+							Name:         "FOO",
+							Literal:      "{\n  \"a\": 1\n}",
+							Interpolated: "{\n  \"a\": 1\n}",
+							Comments: []*ast.Comment{
+								{
+									Value: "# comment",
+									Position: ast.Position{
+										File:      "-",
+										Line:      1,
+										FirstLine: 1,
+										LastLine:  1,
+										Column:    1,
+										EndColumn: 10,
+										Offset:    0,
+										EndOffset: 9,
+									},
+								},
+							},
+							Position: ast.Position{
+								File:      "-",
+								Line:      2,
+								FirstLine: 1,
+								LastLine:  4,
+								Column:    1,
+								EndColumn: 3,
+								Offset:    10,
+								EndOffset: 28,
+							},
+							NameSpan: ast.Span{
+								Line:      2,
+								Column:    1,
+								EndLine:   2,
+								EndColumn: 4,
+								Offset:    10,
+								EndOffset: 13,
+							},
+							AssignSpan: ast.Span{
+								Line:      2,
+								Column:    4,
+								EndLine:   2,
+								EndColumn: 5,
+								Offset:    13,
+								EndOffset: 14,
+							},
+							ValueSpan: ast.Span{
+								Line:      2,
+								Column:    5,
+								EndLine:   4,
+								EndColumn: 3,
+								Offset:    14,
+								EndOffset: 28,
+							},
+							Quote:    token.SingleQuote,
+							Complete: true,
+							Enabled:  true,
+						},
+					},
+				},
+			}}
 
 		for _, tt := range tests {
 			t.Run(tt.name, func(t *testing.T) {
@@ -475,3 +1251,86 @@ func TestParser_Parse(t *testing.T) {
 		}
 	})
 }
+
+func TestParser_Parse_ErrorRecovery(t *testing.T) {
+	t.Parallel()
+
+	input := "A=1\nTHIS@ONE=2\nB=3\n  C=4\nD=5\n"
+
+	t.Run("stops at the first error by default", func(t *testing.T) {
+		t.Parallel()
+
+		document, err := parser.New(t.Context(), scanner.New(input), "-").Parse(t.Context())
+		require.Nil(t, document)
+
+		var diagnostic parser.Diagnostic
+
+		require.ErrorAs(t, err, &diagnostic)
+		require.Equal(t, uint(2), diagnostic.Line)
+		require.Equal(t, 5, diagnostic.Column)
+		require.Equal(t, "-:2:5: unexpected token Illegal(@) - parseRowStatement 2", err.Error())
+	})
+
+	t.Run("collects all errors in recovery mode", func(t *testing.T) {
+		t.Parallel()
+
+		document, err := parser.New(t.Context(), scanner.New(input), "-", parser.WithErrorRecovery(true)).Parse(t.Context())
+		require.NotNil(t, document)
+
+		var diagnostics parser.Diagnostics
+
+		require.ErrorAs(t, err, &diagnostics)
+		require.Equal(
+			t,
+			parser.Diagnostics{
+				{File: "-", Line: 2, Column: 5, Message: "unexpected token Illegal(@) - parseRowStatement 2"},
+				{File: "-", Line: 4, Column: 1, Message: `(B) unexpected statement: SPACE(" ")`},
+			},
+			diagnostics,
+		)
+
+		var names []string
+
+		for _, assignment := range document.AllAssignments() {
+			names = append(names, assignment.Name)
+		}
+
+		require.Equal(t, []string{"A", "B", "D"}, names)
+	})
+}
+
+func TestParser_Parse_Positions(t *testing.T) {
+	t.Parallel()
+
+	input := "################################################################################\n" +
+		"# Gröup\n" +
+		"################################################################################\n" +
+		"#export ÆØÅ='værdi' # note\n" +
+		"KEY=\"a\nbc\"\n"
+
+	document, err := parser.New(t.Context(), scanner.New(input), "-").Parse(t.Context())
+	require.NoError(t, err)
+
+	require.Len(t, document.Groups, 1)
+
+	group := document.Groups[0]
+	require.Equal(t, 1, group.Position.Column)
+	require.Equal(t, 8, group.Position.EndColumn)
+	require.Equal(t, 81, group.Position.Offset)
+	require.Equal(t, 89, group.Position.EndOffset)
+
+	disabled := document.Get("ÆØÅ")
+	require.NotNil(t, disabled)
+	require.Equal(t, uint(4), disabled.Position.Line)
+	require.Equal(t, 1, disabled.Position.Column, "position starts at the '#export' prefix")
+	require.Equal(t, 27, disabled.Position.EndColumn, "position ends after the inline comment")
+	require.Equal(t, ast.Span{Line: 4, Column: 9, EndLine: 4, EndColumn: 12, Offset: 179, EndOffset: 185}, disabled.NameSpan)
+	require.Equal(t, ast.Span{Line: 4, Column: 12, EndLine: 4, EndColumn: 13, Offset: 185, EndOffset: 186}, disabled.AssignSpan)
+	require.Equal(t, ast.Span{Line: 4, Column: 13, EndLine: 4, EndColumn: 20, Offset: 186, EndOffset: 194}, disabled.ValueSpan)
+
+	multiLine := document.Get("KEY")
+	require.NotNil(t, multiLine)
+	require.Equal(t, ast.Span{Line: 5, Column: 5, EndLine: 6, EndColumn: 4, Offset: 206, EndOffset: 212}, multiLine.ValueSpan)
+	require.Equal(t, 4, multiLine.Position.EndColumn)
+	require.Equal(t, 212, multiLine.Position.EndOffset)
+}
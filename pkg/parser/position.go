@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"unicode/utf8"
+
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/token"
+)
+
+// newAssignment creates an assignment from its tokens, including the positional
+// information of the key, the "=" sign and the value.
+//
+// [assign] is nil for "KEY" assignments, and [value] is nil for "KEY" and "KEY=" assignments.
+func newAssignment(identifier token.Token, assign, value *token.Token) *ast.Assignment {
+	line := identifier.LineNumber
+
+	assignment := &ast.Assignment{
+		Name:  identifier.Literal,
+		Quote: token.NoQuote,
+		Position: ast.Position{
+			Line:      line,
+			FirstLine: line,
+			LastLine:  line,
+			Column:    identifier.Column,
+			Offset:    identifier.StartOffset,
+		},
+		// The identifier token includes any "#" or "export" prefix, so count the name from the end
+		NameSpan: ast.Span{
+			Line:      line,
+			Column:    identifier.EndColumn - utf8.RuneCountInString(identifier.Literal),
+			EndLine:   line,
+			EndColumn: identifier.EndColumn,
+			Offset:    identifier.EndOffset - len(identifier.Literal),
+			EndOffset: identifier.EndOffset,
+		},
+	}
+
+	end := assignment.NameSpan
+
+	if assign != nil {
+		assignment.AssignSpan = newSpan(line, *assign)
+
+		// An empty value is located right after the "=" sign
+		assignment.ValueSpan = ast.Span{
+			Line:      line,
+			Column:    assign.EndColumn,
+			EndLine:   line,
+			EndColumn: assign.EndColumn,
+			Offset:    assign.EndOffset,
+			EndOffset: assign.EndOffset,
+		}
+
+		end = assignment.AssignSpan
+	}
+
+	if value != nil {
+		assignment.Literal = value.Literal
+		assignment.Quote = value.Quote
+		assignment.Complete = true
+		assignment.ValueSpan = newSpan(line, *value)
+		assignment.Position.LastLine = value.LineNumber
+
+		end = assignment.ValueSpan
+	}
+
+	assignment.Position.EndColumn = end.EndColumn
+	assignment.Position.EndOffset = end.EndOffset
+
+	return assignment
+}
+
+// newSpan returns the location of [tok], which starts on [line].
+//
+// Tokens (e.g. quoted values) may span multiple lines, in which case
+// the line number of the token is the line it ends on.
+func newSpan(line uint, tok token.Token) ast.Span {
+	return ast.Span{
+		Line:      line,
+		Column:    tok.Column,
+		EndLine:   tok.LineNumber,
+		EndColumn: tok.EndColumn,
+		Offset:    tok.StartOffset,
+		EndOffset: tok.EndOffset,
+	}
+}
@@ -20,6 +20,24 @@ func NewFormatter() *Renderer {
 	return NewRenderer(settings, FormatterHandler)
 }
 
+// NewPreservingFormatter returns a renderer that keeps the original formatting of the file,
+// so only the statements that have been changed (or added) are rendered. See [PreservingOutput].
+//
+// The document must have been given its source via [ast.Document.SetSource].
+func NewPreservingFormatter() *Renderer {
+	settings := Settings{
+		includeDisabled:    true,
+		InterpolatedValues: false,
+		showBlankLines:     false, // Blank lines are kept by the PreservingOutput
+		showColors:         false,
+		showComments:       true,
+		ShowGroupBanners:   true,
+		outputter:          PreservingOutput{},
+	}
+
+	return NewRenderer(settings)
+}
+
 // FormatterHandler is responsible for formatting an .env file according
 // to our opinionated style.
 func FormatterHandler(ctx context.Context, input *HandlerInput) HandlerSignal {
@@ -31,7 +31,7 @@ func (ColorizedOutput) Assignment(ctx context.Context, assignment *ast.Assignmen
 		out.WriteString(printer.Danger().Sprint("#"))
 	}
 
-	if settings.export {
+	if settings.Export(assignment) {
 		out.WriteString(printer.Dark().Sprint("export "))
 	}
 
@@ -44,9 +44,24 @@ func (ColorizedOutput) Assignment(ctx context.Context, assignment *ast.Assignmen
 	out.WriteString(printer.Primary().Sprint(assignment.Name))
 	out.WriteString(printer.Dark().Sprint("="))
 	out.WriteString(printer.Success().Sprint(assignment.Quote))
-	out.WriteString(printer.Warning().Sprint(val))
+
+	// Style each line of multi-line values individually, since styling the value as
+	// a single block would pad all lines to the same width
+	for i, line := range strings.Split(val, "\n") {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+
+		out.WriteString(printer.Warning().Sprint(line))
+	}
+
 	out.WriteString(printer.Success().Sprint(assignment.Quote))
 
+	if len(assignment.InlineComment) > 0 && settings.ShowComments() {
+		out.WriteString(" ")
+		out.WriteString(printer.Success().Sprint(assignment.InlineComment))
+	}
+
 	return NewLinesCollection().Add(out.String())
 }
 
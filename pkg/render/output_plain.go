@@ -29,7 +29,7 @@ func (PlainOutput) Assignment(ctx context.Context, assignment *ast.Assignment, s
 		buf.WriteString("#")
 	}
 
-	if settings.export {
+	if settings.Export(assignment) {
 		buf.WriteString("export ")
 	}
 
@@ -41,6 +41,11 @@ func (PlainOutput) Assignment(ctx context.Context, assignment *ast.Assignment, s
 
 	fmt.Fprintf(&buf, "%s=%s%s%s", assignment.Name, assignment.Quote, val, assignment.Quote)
 
+	if len(assignment.InlineComment) > 0 && settings.ShowComments() {
+		buf.WriteString(" ")
+		buf.WriteString(assignment.InlineComment)
+	}
+
 	return NewLinesCollection().Add(buf.String())
 }
 
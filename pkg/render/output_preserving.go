@@ -0,0 +1,50 @@
+package render
+
+import (
+	"context"
+
+	"github.com/jippi/dottie/pkg/ast"
+)
+
+var _ Output = (*PreservingOutput)(nil)
+
+// PreservingOutput writes statements exactly as they were in the source file, and
+// only renders the statements that have been changed (or added) since it was parsed.
+type PreservingOutput struct {
+	PlainOutput
+}
+
+func (o PreservingOutput) GroupBanner(ctx context.Context, group *ast.Group, settings Settings) *Lines {
+	if header, ok := group.Source(); ok {
+		return NewLinesCollection().Add(header)
+	}
+
+	// New groups get a blank line before and after the header, like the formatter does
+	return NewLinesCollection().
+		Newline("PreservingOutput:GroupBanner:before").
+		Append(o.PlainOutput.GroupBanner(ctx, group, settings)).
+		Newline("PreservingOutput:GroupBanner:after")
+}
+
+func (o PreservingOutput) Assignment(ctx context.Context, assignment *ast.Assignment, settings Settings) *Lines {
+	if source, ok := assignment.Source(); ok {
+		return NewLinesCollection().Add(source)
+	}
+
+	return o.PlainOutput.Assignment(ctx, assignment, settings)
+}
+
+func (o PreservingOutput) Newline(ctx context.Context, newline *ast.Newline, settings Settings) *Lines {
+	if !newline.Blank {
+		return nil
+	}
+
+	// Keep the exact number of blank lines from the source file
+	out := NewLinesCollection()
+
+	for range newline.Repeated + 1 {
+		out.Newline("PreservingOutput:Newline")
+	}
+
+	return out
+}
@@ -68,3 +68,52 @@ func TestFormatter(t *testing.T) {
 		})
 	}
 }
+
+func TestPreservingFormatterRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	files, err := os.ReadDir("test-fixtures/formatter")
+	require.NoError(t, err)
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		t.Run(strings.TrimSuffix(file.Name(), ".env"), func(t *testing.T) {
+			t.Parallel()
+
+			input, err := os.ReadFile("test-fixtures/formatter/" + file.Name())
+			require.NoError(t, err)
+
+			if len(input) == 0 {
+				t.Skip("empty files are never saved")
+			}
+
+			// The original source is only kept when the formatting is preserved
+			env, err := pkg.Load(pkg.WithPreserveFormatting(t.Context(), true), "test-fixtures/formatter/"+file.Name())
+			require.NoError(t, err)
+
+			// The renderer always ends the output with a newline
+			expected := strings.TrimSuffix(string(input), "\n") + "\n"
+
+			require.Equal(t, expected, render.NewPreservingFormatter().Statement(t.Context(), env).String())
+		})
+	}
+}
+
+func TestPreservingFormatterRendersChangedStatements(t *testing.T) {
+	t.Parallel()
+
+	input := "A=1   # keep my spacing\n\n\n\nB=2  # spacing will be normalized\n##########\n# Group\n##########\nC=3\n"
+
+	env, err := pkg.Parse(pkg.WithPreserveFormatting(t.Context(), true), strings.NewReader(input), "-")
+	require.NoError(t, err)
+
+	env.Get("B").Literal = "changed"
+	env.Get("C").Disable()
+
+	expected := "A=1   # keep my spacing\n\n\n\nB=changed # spacing will be normalized\n##########\n# Group\n##########\n#C=3\n"
+
+	require.Equal(t, expected, render.NewPreservingFormatter().Statement(t.Context(), env).String())
+}
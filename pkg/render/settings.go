@@ -21,6 +21,7 @@ type Settings struct {
 	ShowGroupBanners   bool
 	formatOutput       bool
 	export             bool
+	exportOverride     bool
 	InterpolatedValues bool
 	outputter          Output
 }
@@ -43,6 +44,22 @@ func (rs Settings) ShowBlankLines() bool {
 	return rs.formatOutput || (rs.showBlankLines && rs.showComments)
 }
 
+func (rs Settings) ShowComments() bool {
+	return rs.showComments
+}
+
+// Export reports if the assignment should be prefixed with "export".
+//
+// The per-assignment "export" prefix from the source file is used, unless
+// [WithExport] was used to override it for all assignments.
+func (rs Settings) Export(assignment *ast.Assignment) bool {
+	if rs.exportOverride {
+		return rs.export
+	}
+
+	return assignment.Exported
+}
+
 func (rs Settings) Handlers() []ast.Selector {
 	var res []ast.Selector
 
@@ -71,9 +71,12 @@ func WithFormattedOutput(boolean bool) SettingsOption {
 	}
 }
 
+// WithExport overrides the per-assignment "export" prefix for all assignments,
+// either adding it to all of them (true) or removing it from all of them (false)
 func WithExport(boolean bool) SettingsOption {
 	return func(s *Settings) {
 		s.export = boolean
+		s.exportOverride = true
 	}
 }
 
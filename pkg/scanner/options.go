@@ -0,0 +1,52 @@
+package scanner
+
+import "context"
+
+// Limits bounds how much input the scanner will process, a limit of 0 means unlimited.
+type Limits struct {
+	// MaxInputBytes bounds the total size of the input
+	MaxInputBytes int
+
+	// MaxValueBytes bounds the size of a single (quoted or unquoted) value
+	MaxValueBytes int
+}
+
+var (
+	// DefaultLimits are used unless [WithLimits] is provided, and keep pathological
+	// (e.g. fuzzed or untrusted) payloads from exhausting time and memory budgets.
+	DefaultLimits = Limits{
+		MaxInputBytes: 128 * 1024,
+		MaxValueBytes: 64 * 1024,
+	}
+
+	// Unlimited disables all limits, and should only be used for trusted input.
+	Unlimited = Limits{}
+)
+
+type Option func(*Scanner)
+
+// WithLimits replaces the [DefaultLimits] of the scanner
+func WithLimits(limits Limits) Option {
+	return func(s *Scanner) {
+		s.limits = limits
+	}
+}
+
+type contextKey int
+
+const limitsKey contextKey = iota
+
+// ContextWithLimits returns a context where [LimitsFromContext] returns [limits], allowing
+// trusted callers to opt into larger inputs for all files loaded with the context.
+func ContextWithLimits(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, limitsKey, limits)
+}
+
+// LimitsFromContext returns the [Limits] configured with [ContextWithLimits], or [DefaultLimits]
+func LimitsFromContext(ctx context.Context) Limits {
+	if limits, ok := ctx.Value(limitsKey).(Limits); ok {
+		return limits
+	}
+
+	return DefaultLimits
+}
@@ -2,7 +2,10 @@ package scanner
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log/slog"
+	"slices"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -24,42 +27,68 @@ const (
 	bom = 0xFEFF // byte order mark, only permitted as the first character
 	eof = -1     // eof indicates the end of the file.
 
-	// maxInputBytes bounds total scanner input to avoid pathological payloads
-	// exhausting fuzz worker execution budgets during tokenization.
-	maxInputBytes = 128 * 1024
-
-	// maxValueTokenBytes bounds scanner work for a single value token to prevent
-	// pathological inputs from forcing extremely expensive scans during parsing.
-	maxValueTokenBytes = 64 * 1024
+	// readChunkSize is the minimum number of bytes read from the input at a time
+	readChunkSize = 32 * 1024
 
 	scannerInputTooLargeErrorLiteral = "input exceeds maximum supported length"
+
+	// exportKeyword is the (optional) shell prefix for assignments, e.g. "export KEY=VALUE"
+	exportKeyword = "export"
 )
 
 // Scanner converts a sequence of characters into a sequence of tokens.
+//
+// The input is read incrementally, and only the current line (or multi-line value)
+// is kept in memory. All offsets are relative to the start of the input.
 type Scanner struct {
-	input      string
+	reader    io.Reader
+	buf       []byte // the input that has been read, but not yet discarded
+	base      int    // offset of the first byte in buf
+	read      int    // number of bytes read from reader
+	exhausted bool   // no more input can be read from reader
+	readErr   error  // error (other than io.EOF) returned by reader
+	limits    Limits
+
 	rune       rune // current character
 	prevOffset int  // position before current character
 	offset     int  // character offset
 	peekOffset int  // position after current character
 	lineNumber uint // current line number
+	lineStart  int  // offset of the first character on the current line
+	afterValue bool // an assignment value has been scanned on the current line
 
-	inputTooLarge        bool
-	inputTooLargeEmitted bool
+	inputTooLarge bool
+	errorEmitted  bool
 }
 
-// New returns new Scanner.
-func New(input string) *Scanner {
+// New returns new Scanner for the [input] string.
+func New(input string, options ...Option) *Scanner {
+	return NewReader(strings.NewReader(input), options...)
+}
+
+// NewReader returns a new Scanner reading its input from [reader] as it goes.
+func NewReader(reader io.Reader, options ...Option) *Scanner {
 	scanner := &Scanner{
-		input:         input,
-		lineNumber:    1,
-		inputTooLarge: len(input) > maxInputBytes,
+		reader:     reader,
+		lineNumber: 1,
+		limits:     DefaultLimits,
+	}
+
+	for _, option := range options {
+		option(scanner)
+	}
+
+	// Readers knowing their size (e.g. strings.Reader) can be rejected before reading anything
+	if sized, ok := reader.(interface{ Len() int }); ok && scanner.inputLimitExceeded(sized.Len()) {
+		scanner.inputTooLarge = true
+		scanner.exhausted = true
 	}
 
 	scanner.next()
 
 	if scanner.rune == bom {
 		scanner.next() // ignore BOM at the beginning of the file
+		scanner.lineStart = scanner.offset
 	}
 
 	return scanner
@@ -73,18 +102,41 @@ func New(input string) *Scanner {
 //
 // If the returned token is token.Illegal, the literal string is the offending character.
 func (s *Scanner) NextToken(ctx context.Context) token.Token {
-	if s.inputTooLarge && !s.inputTooLargeEmitted {
+	s.discard()
+
+	start, column := s.offset, s.column()
+
+	res := s.scanToken(ctx)
+	res.Column, res.EndColumn = column, s.column()
+	res.StartOffset, res.EndOffset = start, s.offset
+
+	// Inline comments consume the whitespace in front of them, which is not part of the token
+	if res.Type == token.InlineComment {
+		res.StartOffset = res.EndOffset - len(res.Literal)
+		res.Column = res.EndColumn - utf8.RuneCountInString(res.Literal)
+	}
+
+	return res
+}
+
+func (s *Scanner) scanToken(ctx context.Context) token.Token {
+	if (s.inputTooLarge || s.readErr != nil) && !s.errorEmitted {
 		// Fuzzing found oversized payloads where silently truncating input hid
 		// malformed content; emit an explicit illegal token instead so invalid
 		// input fails fast and predictably.
-		s.inputTooLargeEmitted = true
+		s.errorEmitted = true
 		s.rune = eof
 
+		literal := scannerInputTooLargeErrorLiteral
+		if !s.inputTooLarge {
+			literal = "failed to read input: " + s.readErr.Error()
+		}
+
 		return token.New(
 			token.Illegal,
-			token.WithLiteral(scannerInputTooLargeErrorLiteral),
-			token.WithOffset(0),
-			token.WithLineNumber(1),
+			token.WithLiteral(literal),
+			token.WithOffset(s.offset),
+			token.WithLineNumber(s.lineNumber),
 		)
 	}
 
@@ -110,6 +162,10 @@ func (s *Scanner) NextToken(ctx context.Context) token.Token {
 		return s.scanNewLine()
 
 	case ' ', '\t', '\r', '\v', '\f':
+		if s.afterValue && s.isInlineComment() {
+			return s.scanInlineComment()
+		}
+
 		defer s.next()
 
 		return token.New(
@@ -122,6 +178,8 @@ func (s *Scanner) NextToken(ctx context.Context) token.Token {
 	case '=':
 		defer s.next()
 
+		s.afterValue = true
+
 		return token.New(
 			token.Assign,
 			token.WithOffset(s.offset),
@@ -158,9 +216,12 @@ func (s *Scanner) NextToken(ctx context.Context) token.Token {
 
 func (s *Scanner) scanNewLine() token.Token {
 	s.lineNumber++
+	s.afterValue = false
 
 	s.next()
 
+	s.lineStart = s.offset
+
 	return token.New(
 		token.NewLine,
 		token.WithLiteral("\n"),
@@ -176,7 +237,20 @@ func (s *Scanner) scanIdentifier() token.Token {
 		s.next()
 	}
 
-	literal := s.input[start:s.offset]
+	literal := s.text(start, s.offset)
+
+	// Lines like "export KEY=VALUE" are common in files that are also sourced by
+	// a shell, so consume the "export" prefix and return the real identifier
+	if literal == exportKeyword && s.isExportPrefix() {
+		for s.rune == ' ' || s.rune == '\t' {
+			s.next()
+		}
+
+		res := s.scanIdentifier()
+		res.Exported = true
+
+		return res
+	}
 
 	return token.New(
 		token.Identifier,
@@ -214,14 +288,14 @@ func (s *Scanner) scanComment() token.Token {
 
 		return token.New(
 			token.GroupBanner,
-			token.WithLiteral(s.input[start:s.offset]),
+			token.WithLiteral(s.text(start, s.offset)),
 			token.WithOffset(s.offset),
 			token.WithLineNumber(s.lineNumber),
 		)
 	}
 
 	s.untilEndOfLine()
-	lit := s.input[start:s.offset]
+	lit := s.text(start, s.offset)
 
 	return token.New(
 		token.Comment,
@@ -231,6 +305,27 @@ func (s *Scanner) scanComment() token.Token {
 	)
 }
 
+// scanInlineComment scans a trailing comment after an assignment value, e.g.
+// the "# default port" part of "PORT=3306 # default port"
+func (s *Scanner) scanInlineComment() token.Token {
+	s.afterValue = false
+
+	for s.rune == ' ' || s.rune == '\t' {
+		s.next()
+	}
+
+	start := s.offset
+
+	s.untilEndOfLine()
+
+	return token.New(
+		token.InlineComment,
+		token.WithLiteral(s.text(start, s.offset)),
+		token.WithOffset(s.offset),
+		token.WithLineNumber(s.lineNumber),
+	)
+}
+
 func (s *Scanner) scanCommentAnnotation(offset int) token.Token {
 	// Consume the @
 	s.next()
@@ -242,7 +337,7 @@ func (s *Scanner) scanCommentAnnotation(offset int) token.Token {
 		s.next()
 	}
 
-	key := s.input[start:s.offset]
+	key := s.text(start, s.offset)
 
 	// Consume any space between key and value
 	s.skipWhitespace()
@@ -251,11 +346,11 @@ func (s *Scanner) scanCommentAnnotation(offset int) token.Token {
 	valueStart := s.offset
 	s.untilEndOfLine()
 
-	value := s.input[valueStart:s.offset]
+	value := s.text(valueStart, s.offset)
 
 	return token.New(
 		token.CommentAnnotation,
-		token.WithLiteral(s.input[offset:s.offset]), // full line
+		token.WithLiteral(s.text(offset, s.offset)), // full line
 		token.WithOffset(s.offset),
 		token.WithLineNumber(s.lineNumber),
 		token.WithAnnotation(key, value),
@@ -289,7 +384,12 @@ func (s *Scanner) scanUnquotedValue() token.Token {
 	start := s.offset
 
 	for !isEOF(s.rune) && !isNewLine(s.rune) {
-		if s.offset-start >= maxValueTokenBytes {
+		// Whitespace followed by "#" starts an inline comment, just like in a shell
+		if (s.rune == ' ' || s.rune == '\t') && s.isInlineComment() {
+			break
+		}
+
+		if s.valueLimitExceeded(start) {
 			return token.New(
 				token.Illegal,
 				token.WithLiteral("value exceeds maximum supported length"),
@@ -301,7 +401,7 @@ func (s *Scanner) scanUnquotedValue() token.Token {
 		s.next()
 	}
 
-	lit := escape(s.input[start:s.offset])
+	lit := escape(s.text(start, s.offset))
 
 	return token.New(
 		token.Value,
@@ -317,6 +417,7 @@ func (s *Scanner) scanQuotedValue(_ context.Context, tType token.Type, quote tok
 	s.next()
 
 	start := s.offset
+	startLine := s.lineNumber
 
 	escapes := 0
 	foundEndQuote := false
@@ -339,6 +440,13 @@ func (s *Scanner) scanQuotedValue(_ context.Context, tType token.Type, quote tok
 			break
 		}
 
+		// Quoted values may span multiple lines (e.g. PEM keys or JSON blobs),
+		// so keep the line counter in sync with the newlines we consume
+		if isNewLine(s.rune) {
+			s.lineNumber++
+			s.lineStart = s.offset + 1
+		}
+
 		if s.rune == '\\' {
 			escapes++
 		} else {
@@ -349,7 +457,7 @@ func (s *Scanner) scanQuotedValue(_ context.Context, tType token.Type, quote tok
 			escapes = 0
 		}
 
-		if s.offset-start >= maxValueTokenBytes {
+		if s.valueLimitExceeded(start) {
 			tType = token.Illegal
 
 			break
@@ -364,7 +472,7 @@ func (s *Scanner) scanQuotedValue(_ context.Context, tType token.Type, quote tok
 	}
 
 	offset := s.offset
-	lit := s.input[start:offset]
+	lit := s.text(start, offset)
 
 	if tType == token.Value {
 		lit = escape(lit)
@@ -374,12 +482,19 @@ func (s *Scanner) scanQuotedValue(_ context.Context, tType token.Type, quote tok
 		s.next()
 	}
 
+	line := s.lineNumber
+
+	// Report syntax errors on the line with the opening quote, since that's where the value starts
+	if tType == token.Illegal {
+		line = startLine
+	}
+
 	return token.New(
 		tType,
 		token.WithLiteral(lit),
 		token.WithQuoteType(quote),
 		token.WithOffset(offset),
-		token.WithLineNumber(s.lineNumber),
+		token.WithLineNumber(line),
 	)
 }
 
@@ -392,14 +507,14 @@ func (s *Scanner) scanQuotedValue(_ context.Context, tType token.Type, quote tok
 func (s *Scanner) next() {
 	s.prevOffset = s.offset
 
-	if s.peekOffset < len(s.input) {
+	if s.available(s.peekOffset) {
 		s.offset = s.peekOffset
 		r, width := s.scanRune(s.offset)
 
 		s.peekOffset += width
 		s.rune = r
 	} else {
-		s.offset = len(s.input)
+		s.offset = s.peekOffset
 		s.rune = eof
 	}
 
@@ -413,7 +528,7 @@ func (s *Scanner) prev() rune {
 	case s.prevOffset < 0:
 		return '\n'
 
-	case s.prevOffset < len(s.input):
+	case s.available(s.prevOffset):
 		r, _ := s.scanRune(s.prevOffset)
 
 		return r
@@ -425,13 +540,15 @@ func (s *Scanner) prev() rune {
 
 // Reads a single Unicode character and returns the rune and its width in bytes.
 func (s *Scanner) scanRune(offset int) (rune, int) {
-	runeVal := rune(s.input[offset])
+	runeVal := rune(s.buf[offset-s.base])
 	width := 1
 
 	switch {
 	case runeVal >= utf8.RuneSelf:
 		// not ASCII
-		runeVal, width = utf8.DecodeRuneInString(s.input[offset:])
+		s.ensure(offset + utf8.UTFMax)
+
+		runeVal, width = utf8.DecodeRune(s.buf[offset-s.base:])
 		if runeVal == utf8.RuneError && width == 1 {
 			return utf8.RuneError, 1
 		}
@@ -444,22 +561,149 @@ func (s *Scanner) scanRune(offset int) (rune, int) {
 	return runeVal, width
 }
 
+// column returns the (1-based) column of the current character on the current line.
+func (s *Scanner) column() int {
+	if s.offset < s.lineStart {
+		return 1
+	}
+
+	return utf8.RuneCount(s.buf[s.lineStart-s.base:s.offset-s.base]) + 1
+}
+
+// isExportPrefix reports if the current position is the whitespace between an
+// "export" keyword and a valid identifier, without consuming any input.
+func (s *Scanner) isExportPrefix() bool {
+	offset := s.skipBlanks(s.offset)
+
+	if offset == s.offset || !s.available(offset) {
+		return false
+	}
+
+	r, _ := s.scanRune(offset)
+
+	return isValidIdentifier(r)
+}
+
+// isInlineComment reports if the current position is the whitespace before a
+// trailing "#" comment, without consuming any input.
+func (s *Scanner) isInlineComment() bool {
+	offset := s.skipBlanks(s.offset)
+
+	return offset != s.offset && s.available(offset) && s.buf[offset-s.base] == '#'
+}
+
+// skipBlanks returns the offset of the first character from [offset] that isn't a space or tab
+func (s *Scanner) skipBlanks(offset int) int {
+	for s.available(offset) && (s.buf[offset-s.base] == ' ' || s.buf[offset-s.base] == '\t') {
+		offset++
+	}
+
+	return offset
+}
+
 func (s *Scanner) peek(length int) string {
 	start := s.offset
 	end := start + length
 
-	maxLength := len(s.input)
-	if maxLength >= end {
-		return s.input[start:end]
+	if !s.ensure(end) {
+		end = s.base + len(s.buf)
+	}
+
+	return s.text(start, end)
+}
+
+// ========================================================================
+// Methods that manage the buffered input.
+// ========================================================================
+
+// text returns the input between the [start] and [end] offsets
+func (s *Scanner) text(start, end int) string {
+	return string(s.buf[start-s.base : end-s.base])
+}
+
+// available reports if the byte at [offset] exists in the input, reading more input if needed
+func (s *Scanner) available(offset int) bool {
+	return s.ensure(offset + 1)
+}
+
+// ensure reads input until the buffer holds everything before the [end] offset,
+// and reports if it does (it won't at the end of the input).
+func (s *Scanner) ensure(end int) bool {
+	for s.base+len(s.buf) < end && !s.exhausted {
+		s.fill()
+	}
+
+	return s.base+len(s.buf) >= end
+}
+
+func (s *Scanner) fill() {
+	s.buf = slices.Grow(s.buf, readChunkSize)
+
+	n, err := s.reader.Read(s.buf[len(s.buf):cap(s.buf)])
+
+	s.buf = s.buf[:len(s.buf)+n]
+	s.read += n
+
+	if s.inputLimitExceeded(s.read) {
+		s.inputTooLarge = true
+		s.exhausted = true
+	}
+
+	if err != nil {
+		s.exhausted = true
+
+		if !errors.Is(err, io.EOF) {
+			s.readErr = err
+		}
+	}
+}
+
+// discard drops the buffered input that can no longer be referenced; only the current
+// line (for columns) and the previous character (for [Scanner.prev]) are needed.
+func (s *Scanner) discard() {
+	keep := min(s.lineStart, s.offset)
+	if s.prevOffset >= 0 {
+		keep = min(keep, s.prevOffset)
+	}
+
+	// Only move the remaining input once a good chunk of the buffer can be reclaimed
+	drop := keep - s.base
+	if drop < readChunkSize || drop < len(s.buf)/2 {
+		return
 	}
 
-	return s.input[start:maxLength]
+	s.buf = s.buf[:copy(s.buf, s.buf[drop:])]
+	s.base = keep
+}
+
+func (s *Scanner) inputLimitExceeded(size int) bool {
+	return s.limits.MaxInputBytes > 0 && size > s.limits.MaxInputBytes
+}
+
+func (s *Scanner) valueLimitExceeded(start int) bool {
+	return s.limits.MaxValueBytes > 0 && s.offset-start >= s.limits.MaxValueBytes
 }
 
 // ========================================================================
 // Auxiliary methods that check if the rune is one of the specific kind.
 // ========================================================================
 
+// IsValidKey reports if [name] is scanned as a single KEY, when used as "KEY=VALUE" on a line of its own
+func IsValidKey(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+
+	scan := New(name + "=")
+
+	identifier := scan.NextToken(context.Background())
+	if identifier.Type != token.Identifier || identifier.Literal != name {
+		return false
+	}
+
+	return scan.NextToken(context.Background()).Type == token.Assign
+}
+
 func isValidIdentifier(r rune) bool {
 	return isLetter(r) || isDigit(r) || isSymbol(r)
 }
@@ -1,8 +1,10 @@
 package scanner_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/jippi/dottie/pkg/scanner"
 	"github.com/jippi/dottie/pkg/token"
@@ -466,3 +468,244 @@ func TestScanner_NextToken(t *testing.T) {
 		})
 	}
 }
+
+func TestScanner_NextToken_MultiLineValueTracksLineNumbers(t *testing.T) {
+	t.Parallel()
+
+	sc := scanner.New("x=\"a\nb\nc\"\ny=z\n")
+
+	expected := []struct {
+		tokenType  token.Type
+		literal    string
+		lineNumber uint
+	}{
+		{token.Identifier, "x", 1},
+		{token.Assign, token.Assign.String(), 1},
+		{token.Value, "a\nb\nc", 3},
+		{token.NewLine, "\n", 3},
+		{token.Identifier, "y", 4},
+		{token.Assign, token.Assign.String(), 4},
+		{token.Value, "z", 4},
+		{token.NewLine, "\n", 4},
+		{token.EOF, token.EOF.String(), 5},
+	}
+
+	for _, want := range expected {
+		actual := sc.NextToken(t.Context())
+
+		assert.Equal(t, want.tokenType, actual.Type)
+		assert.Equal(t, want.literal, actual.Literal)
+		assert.Equal(t, want.lineNumber, actual.LineNumber, "line number for %s(%q)", actual.Type, actual.Literal)
+	}
+}
+
+func TestScanner_NextToken_ExportPrefix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		input            string
+		expectedLiteral  string
+		expectedExported bool
+		expectedComment  bool
+	}{
+		{
+			name:             "export prefix",
+			input:            "export FOO=bar",
+			expectedLiteral:  "FOO",
+			expectedExported: true,
+		},
+		{
+			name:             "export prefix with tabs and spaces",
+			input:            "export \t FOO=bar",
+			expectedLiteral:  "FOO",
+			expectedExported: true,
+		},
+		{
+			name:             "disabled export prefix",
+			input:            "#export FOO=bar",
+			expectedLiteral:  "FOO",
+			expectedExported: true,
+			expectedComment:  true,
+		},
+		{
+			name:            "export as key name",
+			input:           "export=value",
+			expectedLiteral: "export",
+		},
+		{
+			name:            "export as key name followed by space",
+			input:           "export =value",
+			expectedLiteral: "export",
+		},
+		{
+			name:            "identifier starting with export",
+			input:           "exported=value",
+			expectedLiteral: "exported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := scanner.New(tt.input).NextToken(t.Context())
+
+			assert.Equal(t, token.Identifier, actual.Type)
+			assert.Equal(t, tt.expectedLiteral, actual.Literal)
+			assert.Equal(t, tt.expectedExported, actual.Exported)
+			assert.Equal(t, tt.expectedComment, actual.Commented)
+		})
+	}
+}
+
+func TestScanner_NextToken_InlineComment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []token.Token
+	}{
+		{
+			name:  "naked value",
+			input: "PORT=3306 # default port",
+			expected: []token.Token{
+				{Type: token.Identifier, Literal: "PORT"},
+				{Type: token.Assign, Literal: token.Assign.String()},
+				{Type: token.Value, Literal: "3306"},
+				{Type: token.InlineComment, Literal: "# default port"},
+				{Type: token.EOF, Literal: token.EOF.String()},
+			},
+		},
+		{
+			name:  "quoted value",
+			input: "HOST='local # host'\t# the host\n",
+			expected: []token.Token{
+				{Type: token.Identifier, Literal: "HOST"},
+				{Type: token.Assign, Literal: token.Assign.String()},
+				{Type: token.RawValue, Literal: "local # host"},
+				{Type: token.InlineComment, Literal: "# the host"},
+				{Type: token.NewLine, Literal: "\n"},
+				{Type: token.EOF, Literal: token.EOF.String()},
+			},
+		},
+		{
+			name:  "hash without whitespace",
+			input: "COLOR=red#fff",
+			expected: []token.Token{
+				{Type: token.Identifier, Literal: "COLOR"},
+				{Type: token.Assign, Literal: token.Assign.String()},
+				{Type: token.Value, Literal: "red#fff"},
+				{Type: token.EOF, Literal: token.EOF.String()},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sc := scanner.New(tt.input)
+
+			for _, want := range tt.expected {
+				actual := sc.NextToken(t.Context())
+
+				assert.Equal(t, want.Type, actual.Type)
+				assert.Equal(t, want.Literal, actual.Literal)
+			}
+		})
+	}
+}
+
+func TestScanner_NewReader_MatchesStringInput(t *testing.T) {
+	t.Parallel()
+
+	// Enough lines to cross multiple read chunks, so the buffer is both grown and compacted
+	var builder strings.Builder
+
+	builder.WriteString("\uFEFF# @dottie/source .env.example\n")
+
+	for i := range 5000 {
+		builder.WriteString("export KEY_" + strings.Repeat("x", i%7) + "=\"välue\nline two\" # comment\n")
+		builder.WriteString("#DISABLED=naked value\n\n")
+	}
+
+	input := builder.String()
+
+	expected := scanner.New(input, scanner.WithLimits(scanner.Unlimited))
+	actual := scanner.NewReader(iotest.HalfReader(strings.NewReader(input)), scanner.WithLimits(scanner.Unlimited))
+
+	for {
+		want := expected.NextToken(t.Context())
+		got := actual.NextToken(t.Context())
+
+		if !assert.Equal(t, want, got) || want.Type == token.EOF {
+			break
+		}
+	}
+}
+
+func TestScanner_NewReader_WithLimits(t *testing.T) {
+	t.Parallel()
+
+	input := "A=\"" + strings.Repeat("a", oversizedValuePadding) + "\"\n# " + strings.Repeat("b", oversizedInputPadding)
+
+	t.Run("default limits", func(t *testing.T) {
+		t.Parallel()
+
+		sc := scanner.NewReader(iotest.OneByteReader(strings.NewReader(input)))
+
+		assert.Equal(t, token.Identifier, sc.NextToken(t.Context()).Type)
+		assert.Equal(t, token.Assign, sc.NextToken(t.Context()).Type)
+		assert.Equal(t, token.Illegal, sc.NextToken(t.Context()).Type)
+	})
+
+	t.Run("input limit exceeded while reading", func(t *testing.T) {
+		t.Parallel()
+
+		sc := scanner.NewReader(
+			iotest.OneByteReader(strings.NewReader(input)),
+			scanner.WithLimits(scanner.Limits{MaxInputBytes: 250 * 1024}),
+		)
+
+		assert.Equal(t, token.Identifier, sc.NextToken(t.Context()).Type)
+		assert.Equal(t, token.Assign, sc.NextToken(t.Context()).Type)
+		assert.Equal(t, token.Value, sc.NextToken(t.Context()).Type)
+		assert.Equal(t, token.NewLine, sc.NextToken(t.Context()).Type)
+		assert.Equal(t, token.Comment, sc.NextToken(t.Context()).Type)
+
+		actual := sc.NextToken(t.Context())
+		assert.Equal(t, token.Illegal, actual.Type)
+		assert.Equal(t, "input exceeds maximum supported length", actual.Literal)
+		assert.Equal(t, token.EOF, sc.NextToken(t.Context()).Type)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		t.Parallel()
+
+		sc := scanner.NewReader(strings.NewReader(input), scanner.WithLimits(scanner.Unlimited))
+
+		assert.Equal(t, token.Identifier, sc.NextToken(t.Context()).Type)
+		assert.Equal(t, token.Assign, sc.NextToken(t.Context()).Type)
+
+		value := sc.NextToken(t.Context())
+		assert.Equal(t, token.Value, value.Type)
+		assert.Len(t, value.Literal, oversizedValuePadding)
+
+		assert.Equal(t, token.NewLine, sc.NextToken(t.Context()).Type)
+		assert.Equal(t, token.Comment, sc.NextToken(t.Context()).Type)
+		assert.Equal(t, token.EOF, sc.NextToken(t.Context()).Type)
+	})
+}
+
+func TestScanner_NewReader_ReadErrorReturnsIllegal(t *testing.T) {
+	t.Parallel()
+
+	sc := scanner.NewReader(iotest.ErrReader(errors.New("disk on fire")))
+
+	actual := sc.NextToken(t.Context())
+	assert.Equal(t, token.Illegal, actual.Type)
+	assert.Equal(t, "failed to read input: disk on fire", actual.Literal)
+	assert.Equal(t, token.EOF, sc.NextToken(t.Context()).Type)
+}
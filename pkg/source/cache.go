@@ -0,0 +1,171 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jippi/dottie/pkg"
+)
+
+// Cache is a content-addressed cache of fetched sources.
+//
+// The content of each source is stored as [Dir]/blobs/<sha256>, and the latest fetch
+// of each (resolved) source is recorded in [Dir]/sources/<sha256 of the resolved source>.json
+type Cache struct {
+	Dir     string        // The directory to store the cache in
+	TTL     time.Duration // How long a cached source can be used without fetching it again (0 means always fetch)
+	Offline bool          // Never fetch sources, always use the cached copy
+}
+
+// cacheEntry records the latest fetch of a source
+type cacheEntry struct {
+	Source    string    `json:"source"`
+	Resolved  string    `json:"resolved"`
+	SHA256    string    `json:"sha256"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// DefaultCacheDir returns the [DOTTIE_CACHE_DIR] environment variable if set,
+// or a "dottie/sources" directory in the user cache directory
+func DefaultCacheDir() (string, error) {
+	if dir, ok := os.LookupEnv("DOTTIE_CACHE_DIR"); ok && len(dir) > 0 {
+		return dir, nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "dottie", "sources"), nil
+}
+
+type cacheContextKey int
+
+const cacheKey cacheContextKey = iota
+
+// ContextWithCache returns a context where [Fetch] reads and writes the [cache]
+func ContextWithCache(ctx context.Context, cache *Cache) context.Context {
+	return context.WithValue(ctx, cacheKey, cache)
+}
+
+// CacheFromContext returns the [Cache] configured with [ContextWithCache], or nil if sources shouldn't be cached
+func CacheFromContext(ctx context.Context) *Cache {
+	cache, _ := ctx.Value(cacheKey).(*Cache)
+
+	return cache
+}
+
+// lookup returns the cached copy of the [resolved] source, if any
+func (cache *Cache) lookup(resolved string) (*cacheEntry, error) {
+	content, err := os.ReadFile(cache.entryPath(resolved))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(content, entry); err != nil {
+		return nil, fmt.Errorf("could not parse cache entry for [ %s ]: %w", resolved, err)
+	}
+
+	// The blob might have been removed by pruning the cache directory
+	if _, err := os.Stat(cache.blobPath(entry.SHA256)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// fresh reports if the [entry] can be used without fetching the source again
+func (cache *Cache) fresh(entry *cacheEntry) bool {
+	return cache.Offline || (cache.TTL > 0 && time.Since(entry.FetchedAt) < cache.TTL)
+}
+
+// restore copies the cached content of the [entry] to [dst]
+func (cache *Cache) restore(entry *cacheEntry, dst string) error {
+	return copyFile(cache.blobPath(entry.SHA256), dst)
+}
+
+// store adds the fetched [src] file to the cache
+func (cache *Cache) store(source, resolved, src string) error {
+	checksum, err := Checksum(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(cache.Dir, "blobs"), 0o700); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(cache.Dir, "sources"), 0o700); err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if err := pkg.WriteFile(cache.blobPath(checksum), content, 0o600); err != nil {
+		return err
+	}
+
+	entry, err := json.MarshalIndent(cacheEntry{
+		Source:    source,
+		Resolved:  resolved,
+		SHA256:    checksum,
+		FetchedAt: time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return pkg.WriteFile(cache.entryPath(resolved), entry, 0o600)
+}
+
+func (cache *Cache) blobPath(checksum string) string {
+	return filepath.Join(cache.Dir, "blobs", checksum)
+}
+
+func (cache *Cache) entryPath(resolved string) string {
+	hash := sha256.Sum256([]byte(resolved))
+
+	return filepath.Join(cache.Dir, "sources", hex.EncodeToString(hash[:])+".json")
+}
+
+func copyFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
+
+		return err
+	}
+
+	return dstFile.Close()
+}
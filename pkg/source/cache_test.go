@@ -0,0 +1,82 @@
+package source_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jippi/dottie/pkg/source"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchWithCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "source.env")
+	dst := filepath.Join(dir, "fetched.env")
+	cache := &source.Cache{Dir: filepath.Join(dir, "cache")}
+
+	ctx := source.ContextWithCache(t.Context(), cache)
+
+	fetch := func() string {
+		t.Helper()
+
+		resolved, err := source.Fetch(ctx, src, dst)
+		require.NoError(t, err)
+		require.Equal(t, src, resolved)
+
+		content, err := os.ReadFile(dst)
+		require.NoError(t, err)
+
+		return string(content)
+	}
+
+	// Nothing is cached yet
+	cache.Offline = true
+
+	_, err := source.Fetch(ctx, src, dst)
+	require.ErrorContains(t, err, "is not in the cache")
+
+	// Fetching a source adds it to the cache
+	cache.Offline = false
+
+	require.NoError(t, os.WriteFile(src, []byte("KEY=v1\n"), 0o600))
+	require.Equal(t, "KEY=v1\n", fetch())
+
+	// Without a TTL, sources are always fetched again
+	require.NoError(t, os.WriteFile(src, []byte("KEY=v2\n"), 0o600))
+	require.Equal(t, "KEY=v2\n", fetch())
+
+	// Within the TTL, the cached copy is used
+	cache.TTL = time.Hour
+
+	require.NoError(t, os.WriteFile(src, []byte("KEY=v3\n"), 0o600))
+	require.Equal(t, "KEY=v2\n", fetch())
+
+	// Offline, the cached copy is used even if the source is gone
+	cache.TTL = 0
+	cache.Offline = true
+
+	require.NoError(t, os.Remove(src))
+	require.Equal(t, "KEY=v2\n", fetch())
+
+	blobs, err := os.ReadDir(filepath.Join(cache.Dir, "blobs"))
+	require.NoError(t, err)
+	require.Len(t, blobs, 2, "expected a blob per distinct content")
+}
+
+func TestFetchWithoutCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "source.env")
+
+	require.NoError(t, os.WriteFile(src, []byte("KEY=value\n"), 0o600))
+
+	_, err := source.Fetch(t.Context(), src, filepath.Join(dir, "fetched.env"))
+	require.NoError(t, err)
+
+	require.Nil(t, source.CacheFromContext(t.Context()))
+}
@@ -0,0 +1,106 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jippi/dottie/pkg"
+	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/ast/upsert"
+)
+
+// Layer is a fetched and parsed source
+type Layer struct {
+	Source   string        // The source as configured (CLI flag or annotation)
+	Resolved string        // The source as resolved by go-getter
+	SHA256   string        // The checksum of the source content
+	Cached   bool          // If the cached copy of the source was used (see [Cache])
+	Document *ast.Document // The parsed source
+}
+
+// FetchLayer fetches and parses the [src] file
+func FetchLayer(ctx context.Context, src string) (*Layer, error) {
+	tmp, err := os.CreateTemp(os.TempDir(), ".dottie.source")
+	if err != nil {
+		return nil, err
+	}
+
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	resolved, cached, err := fetch(ctx, src, tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := Checksum(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	document, err := pkg.Parse(ctx, file, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Layer{
+		Source:   src,
+		Resolved: resolved,
+		SHA256:   checksum,
+		Cached:   cached,
+		Document: document,
+	}, nil
+}
+
+// Merge merges the [layers] into the document of the first layer, in order, so later layers take precedence.
+//
+// Keys from later layers override the value of existing keys (and their comments, if they have any),
+// and new keys are added to the end of their group.
+//
+// The returned map has the [Layer.Source] each KEY got its value from.
+func Merge(ctx context.Context, layers []*Layer) (*ast.Document, map[string]string, error) {
+	if len(layers) == 0 {
+		return nil, nil, fmt.Errorf("no sources to merge")
+	}
+
+	merged := layers[0].Document
+	attribution := map[string]string{}
+
+	for _, assignment := range merged.AllAssignments() {
+		attribution[assignment.Name] = layers[0].Source
+	}
+
+	for _, layer := range layers[1:] {
+		for _, assignment := range layer.Document.AllAssignments() {
+			upserter, err := upsert.New(
+				merged,
+				upsert.DisableSetting(upsert.Validate),
+				upsert.EnableSettingIf(upsert.UpdateComments, len(assignment.Comments) > 0),
+			)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if assignment.Group != nil {
+				if err := upserter.ApplyOptions(upsert.WithGroup(assignment.Group.String())); err != nil {
+					return nil, nil, err
+				}
+			}
+
+			if _, err := upserter.Upsert(ctx, assignment); err != nil {
+				return nil, nil, fmt.Errorf("could not merge [ %s ] from source [ %s ]: %w", assignment.Name, layer.Source, err)
+			}
+
+			attribution[assignment.Name] = layer.Source
+		}
+	}
+
+	return merged, attribution, nil
+}
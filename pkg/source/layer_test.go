@@ -0,0 +1,67 @@
+package source_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jippi/dottie/pkg/render"
+	"github.com/jippi/dottie/pkg/source"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeLayers(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+
+	base := write("base.env", "# The name of the app\nAPP_NAME=base\nAPP_PORT=80\n")
+	service := write("service.env", "APP_NAME=service\n\n################################################################################\n# Database\n################################################################################\n\nDB_HOST=db\n")
+	overlay := write("overlay.env", "# The public port\nAPP_PORT=443\n")
+
+	var layers []*source.Layer
+
+	for _, src := range []string{base, service, overlay} {
+		layer, err := source.FetchLayer(t.Context(), src)
+		require.NoError(t, err)
+		require.Len(t, layer.SHA256, 64)
+
+		layers = append(layers, layer)
+	}
+
+	merged, attribution, err := source.Merge(t.Context(), layers)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"APP_NAME": service,
+		"APP_PORT": overlay,
+		"DB_HOST":  service,
+	}, attribution)
+
+	require.Equal(t, `# The name of the app
+APP_NAME=service
+
+# The public port
+APP_PORT=443
+
+################################################################################
+# Database
+################################################################################
+
+DB_HOST=db
+`, render.NewFormatter().Statement(t.Context(), merged).String())
+}
+
+func TestMergeWithoutLayers(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := source.Merge(t.Context(), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jippi/dottie/pkg"
+)
+
+// LockFile pins the content of the sources a file was updated from
+type LockFile struct {
+	Sources []*LockedSource `json:"sources"`
+}
+
+// LockedSource is a single source in a [LockFile]
+type LockedSource struct {
+	Source    string    `json:"source"`     // The source as configured (CLI flag or annotation)
+	Resolved  string    `json:"resolved"`   // The source as resolved by go-getter
+	SHA256    string    `json:"sha256"`     // The checksum of the source content
+	FetchedAt time.Time `json:"fetched_at"` // When the source was fetched
+}
+
+// NewLockFile creates a [LockFile] for the [layers], fetched at [fetchedAt]
+func NewLockFile(layers []*Layer, fetchedAt time.Time) *LockFile {
+	lock := &LockFile{}
+
+	for _, layer := range layers {
+		lock.Sources = append(lock.Sources, &LockedSource{
+			Source:    layer.Source,
+			Resolved:  layer.Resolved,
+			SHA256:    layer.SHA256,
+			FetchedAt: fetchedAt,
+		})
+	}
+
+	return lock
+}
+
+// LockFilePath returns the default lock file path for [filename]
+func LockFilePath(filename string) string {
+	return filename + ".dottie-sources.lock"
+}
+
+// ReadLockFile reads the lock file at [path]
+func ReadLockFile(path string) (*LockFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &LockFile{}
+	if err := json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("could not parse lock file [ %s ]: %w", path, err)
+	}
+
+	return lock, nil
+}
+
+// Write writes the lock file to [path]
+func (lock *LockFile) Write(path string) error {
+	content, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return pkg.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// Matches reports if the [other] lock has the same sources, in the same order and with the same content
+func (lock *LockFile) Matches(other *LockFile) bool {
+	if len(lock.Sources) != len(other.Sources) {
+		return false
+	}
+
+	for idx, source := range lock.Sources {
+		if source.Source != other.Sources[idx].Source || source.SHA256 != other.Sources[idx].SHA256 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Changes describes how the [other] lock differs from this one
+func (lock *LockFile) Changes(other *LockFile) []string {
+	var changes []string
+
+	for _, source := range other.Sources {
+		switch locked := lock.Find(source.Source); {
+		case locked == nil:
+			changes = append(changes, fmt.Sprintf("[ %s ] is not in the lock file", source.Source))
+
+		case locked.SHA256 != source.SHA256:
+			changes = append(changes, fmt.Sprintf("[ %s ] changed since it was locked (sha256 %s, locked sha256 %s)", source.Source, source.SHA256, locked.SHA256))
+		}
+	}
+
+	for _, locked := range lock.Sources {
+		if other.Find(locked.Source) == nil {
+			changes = append(changes, fmt.Sprintf("[ %s ] was removed since it was locked", locked.Source))
+		}
+	}
+
+	if len(changes) == 0 && !lock.Matches(other) {
+		changes = append(changes, "the order of the sources changed since they were locked")
+	}
+
+	return changes
+}
+
+// Find returns the locked [source], or nil if it isn't in the lock file
+func (lock *LockFile) Find(source string) *LockedSource {
+	for _, locked := range lock.Sources {
+		if locked.Source == source {
+			return locked
+		}
+	}
+
+	return nil
+}
+
+// Checksum returns the hex encoded sha256 checksum of the file at [path]
+func Checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,75 @@
+package source_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jippi/dottie/pkg/source"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFileRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	sourceFile := filepath.Join(dir, "source.env")
+
+	require.NoError(t, os.WriteFile(sourceFile, []byte("KEY=value\n"), 0o600))
+
+	checksum, err := source.Checksum(sourceFile)
+	require.NoError(t, err)
+	require.Equal(t, "c283007d8774ef7af9ef9242045d49e726f834624768abf670d1e9a6634ee651", checksum)
+
+	lock := &source.LockFile{
+		Sources: []*source.LockedSource{
+			{
+				Source:    "source.env",
+				Resolved:  sourceFile,
+				SHA256:    checksum,
+				FetchedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		},
+	}
+
+	lockFile := source.LockFilePath(filepath.Join(dir, ".env"))
+	require.Equal(t, filepath.Join(dir, ".env.dottie-sources.lock"), lockFile)
+	require.NoError(t, lock.Write(lockFile))
+
+	read, err := source.ReadLockFile(lockFile)
+	require.NoError(t, err)
+	require.Equal(t, lock, read)
+	require.True(t, lock.Matches(read))
+
+	require.Empty(t, lock.Changes(read))
+
+	changed := &source.LockFile{
+		Sources: []*source.LockedSource{
+			{Source: "source.env", SHA256: "other"},
+			{Source: "overlay.env", SHA256: "overlay"},
+		},
+	}
+
+	require.False(t, lock.Matches(changed))
+	require.Equal(t, []string{
+		"[ source.env ] changed since it was locked (sha256 other, locked sha256 " + checksum + ")",
+		"[ overlay.env ] is not in the lock file",
+	}, lock.Changes(changed))
+	require.Equal(t, []string{"[ overlay.env ] was removed since it was locked"}, changed.Changes(&source.LockFile{Sources: changed.Sources[:1]}))
+}
+
+func TestReadLockFileErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	_, err := source.ReadLockFile(filepath.Join(dir, "missing.lock"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	invalid := filepath.Join(dir, "invalid.lock")
+	require.NoError(t, os.WriteFile(invalid, []byte("not json"), 0o600))
+
+	_, err = source.ReadLockFile(invalid)
+	require.ErrorContains(t, err, "could not parse lock file")
+}
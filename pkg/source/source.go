@@ -0,0 +1,104 @@
+// Package source fetches upstream .env files, such as the one named in a [@dottie/source] annotation.
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/go-getter/v2"
+)
+
+// Annotation is the document annotation naming the upstream source of a file
+const Annotation = "dottie/source"
+
+// Fetch copies the [src] file (a local path or any URL supported by go-getter) to [dst],
+// returning the resolved source (e.g. [/abs/path] for local files).
+//
+// If the context has a [Cache] (see [ContextWithCache]), the cached copy is used when it's fresh (or offline),
+// and fetched sources are added to the cache.
+func Fetch(ctx context.Context, src, dst string) (string, error) {
+	resolved, _, err := fetch(ctx, src, dst)
+
+	return resolved, err
+}
+
+// fetch is [Fetch], but also reports if the cached copy of the source was used
+func fetch(ctx context.Context, src, dst string) (string, bool, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return "", false, fmt.Errorf("error getting working directory: %w", err)
+	}
+
+	client := getter.Client{
+		DisableSymlinks: true,
+		Getters: []getter.Getter{
+			&getter.FileGetter{},
+			&getter.GitGetter{},
+			&getter.HttpGetter{
+				XTerraformGetDisabled: true,
+				Netrc:                 true,
+				DoNotCheckHeadFirst:   true,
+			},
+		},
+	}
+
+	request := &getter.Request{
+		Pwd:     pwd,
+		GetMode: getter.ModeFile,
+		Dst:     dst,
+		Src:     src,
+	}
+
+	cache := CacheFromContext(ctx)
+	if cache != nil {
+		resolved, err := resolve(*request, client.Getters)
+		if err != nil {
+			return "", false, err
+		}
+
+		entry, err := cache.lookup(resolved)
+		if err != nil {
+			return "", false, err
+		}
+
+		switch {
+		case entry != nil && cache.fresh(entry):
+			return resolved, true, cache.restore(entry, dst)
+
+		case cache.Offline:
+			return "", false, fmt.Errorf("source [ %s ] is not in the cache [ %s ], it must be fetched once without [--offline] first", src, cache.Dir)
+		}
+	}
+
+	if _, err = client.Get(ctx, request); err != nil {
+		return "", false, err
+	}
+
+	// The getters update the request source to the detected (resolved) source
+	if cache != nil {
+		if err := cache.store(src, request.Src, dst); err != nil {
+			return "", false, fmt.Errorf("could not cache source [ %s ]: %w", src, err)
+		}
+	}
+
+	return request.Src, false, nil
+}
+
+// resolve returns the source of the [request] as detected by the first matching getter, without fetching it
+func resolve(request getter.Request, getters []getter.Getter) (string, error) {
+	for _, candidate := range getters {
+		attempt := request
+
+		ok, err := getter.Detect(&attempt, candidate)
+		if err != nil {
+			return "", err
+		}
+
+		if ok {
+			return attempt.Src, nil
+		}
+	}
+
+	return request.Src, nil
+}
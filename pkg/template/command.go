@@ -0,0 +1,154 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// DefaultCommandTimeout is how long a command substitution may run, unless [CommandSubstitution.Timeout] is set
+const DefaultCommandTimeout = 10 * time.Second
+
+// CommandSubstitution allows [Substitute] to run $(...) command substitutions, instead of keeping them verbatim.
+//
+// Commands run in a restricted environment, which only has the variables of the document
+// the interpolated value can access, and PATH so the commands can be found.
+type CommandSubstitution struct {
+	Timeout time.Duration // How long each command may run (0 means [DefaultCommandTimeout])
+
+	mu       sync.Mutex
+	executed []string
+}
+
+type commandSubstitutionContextKey int
+
+const commandSubstitutionKey commandSubstitutionContextKey = iota
+
+// ContextWithCommandSubstitution returns a context where [Substitute] runs command substitutions with the [substitution] settings
+func ContextWithCommandSubstitution(ctx context.Context, substitution *CommandSubstitution) context.Context {
+	return context.WithValue(ctx, commandSubstitutionKey, substitution)
+}
+
+// CommandSubstitutionFromContext returns the [CommandSubstitution] configured with [ContextWithCommandSubstitution],
+// or nil if command substitutions should be kept verbatim
+func CommandSubstitutionFromContext(ctx context.Context) *CommandSubstitution {
+	substitution, _ := ctx.Value(commandSubstitutionKey).(*CommandSubstitution)
+
+	return substitution
+}
+
+// Executed returns every command that was run, in order
+func (c *CommandSubstitution) Executed() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.executed...)
+}
+
+// run executes the command substitution [node] from the [input] template, and writes its output to [writer]
+func (c *CommandSubstitution) run(ctx context.Context, writer io.Writer, input string, node *syntax.CmdSubst, variables map[string]string) error {
+	command := commandText(input, node)
+
+	c.mu.Lock()
+	c.executed = append(c.executed, command)
+	c.mu.Unlock()
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	environment := make([]string, 0, len(variables)+1)
+
+	if path, ok := os.LookupEnv("PATH"); ok {
+		environment = append(environment, "PATH="+path)
+	}
+
+	for name, value := range variables {
+		environment = append(environment, name+"="+value)
+	}
+
+	var stderr bytes.Buffer
+
+	runner, err := interp.New(
+		interp.StdIO(nil, writer, &stderr),
+		interp.Env(expand.ListEnviron(environment...)),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := runner.Run(ctx, &syntax.File{Stmts: node.Stmts}); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("command substitution [ %s ] timed out after %s", command, timeout)
+		}
+
+		if output := strings.TrimSpace(stderr.String()); len(output) > 0 {
+			return fmt.Errorf("command substitution [ %s ] failed: %w: %s", command, err, output)
+		}
+
+		return fmt.Errorf("command substitution [ %s ] failed: %w", command, err)
+	}
+
+	return nil
+}
+
+// CommandVariables returns the names of the variables used by the command substitutions in [input],
+// which the commands can read from their environment
+func CommandVariables(input string) []string {
+	words, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Document(strings.NewReader(input))
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+
+	syntax.Walk(words, func(node syntax.Node) bool {
+		command, ok := node.(*syntax.CmdSubst)
+		if !ok {
+			return true
+		}
+
+		syntax.Walk(command, func(node syntax.Node) bool {
+			if param, ok := node.(*syntax.ParamExp); ok && param.Param != nil && !slices.Contains(names, param.Param.Value) {
+				names = append(names, param.Param.Value)
+			}
+
+			return true
+		})
+
+		return false
+	})
+
+	return names
+}
+
+// commandText returns the command substitution [node] as written in the [input] template
+func commandText(input string, node *syntax.CmdSubst) string {
+	start := node.Left.Offset() + 2 // $(
+	if node.Backquotes {
+		start = node.Left.Offset() + 1 // `
+	}
+
+	end := node.Right.Offset()
+
+	if start > end || end > uint(len(input)) {
+		return ""
+	}
+
+	return strings.TrimSpace(input[start:end])
+}
@@ -23,6 +23,11 @@ type EnvironmentHelper struct {
 	Resolver            Resolver
 	AccessibleVariables AccessibleVariables
 	MissingKeyCallback  func(string)
+
+	// LookupEnv is consulted for variables the Resolver doesn't know, and filters the process environment in [Each].
+	//
+	// When nil, the process environment is left to the Resolver (e.g. to honor an env policy).
+	LookupEnv func(string) (string, bool)
 }
 
 func (helper EnvironmentHelper) Get(name string) expand.Variable {
@@ -36,12 +41,14 @@ func (helper EnvironmentHelper) Get(name string) expand.Variable {
 		}
 	}
 
-	if val, ok := os.LookupEnv(name); ok {
-		return expand.Variable{
-			Set:      true,
-			Str:      val,
-			Exported: true,
-			Kind:     expand.String,
+	if helper.LookupEnv != nil {
+		if val, ok := helper.LookupEnv(name); ok {
+			return expand.Variable{
+				Set:      true,
+				Str:      val,
+				Exported: true,
+				Kind:     expand.String,
+			}
 		}
 	}
 
@@ -101,12 +108,21 @@ func (l EnvironmentHelper) Each(callback func(name string, vr expand.Variable) b
 		})
 	}
 
+	if l.LookupEnv == nil {
+		return
+	}
+
 	for _, v := range os.Environ() {
 		parts := strings.SplitN(v, "=", 2)
 
+		val, ok := l.LookupEnv(parts[0])
+		if !ok {
+			continue
+		}
+
 		callback(parts[0], expand.Variable{
 			Set:      true,
-			Str:      parts[1],
+			Str:      val,
 			Exported: true,
 			ReadOnly: false,
 			Kind:     expand.String,
@@ -118,30 +134,35 @@ func DefaultMissingKeyCallback(ctx context.Context, input string) func(string) {
 	variables := ExtractVariables(ctx, input)
 
 	return func(key string) {
-		variable, ok := variables[key]
-
-		// shouldn't be a lookup for anything that
-		if !ok {
+		if isMissing(variables, key) {
 			slogctx.Warn(ctx, fmt.Sprintf("The [ $%s ] key is not set. Defaulting to a blank string.", key))
-
-			return
 		}
+	}
+}
 
-		// Required variables are errors, so we ignore them as warnings
-		if variable.Required {
-			return
-		}
+// isMissing reports if the unset variable [key] expands to a blank string, given the [variables] referenced in the template
+func isMissing(variables map[string]Variable, key string) bool {
+	variable, ok := variables[key]
 
-		// If the variable has a default value, then it's not missing
-		if len(variable.DefaultValue) > 0 {
-			return
-		}
+	// shouldn't be a lookup for anything that
+	if !ok {
+		return true
+	}
 
-		// If the variable has a alternate/presence value, then it's not missing
-		if len(variable.PresenceValue) > 0 {
-			return
-		}
+	// Required variables are errors, so we ignore them as warnings
+	if variable.Required {
+		return false
+	}
 
-		slogctx.Warn(ctx, fmt.Sprintf("The [ $%s ] key is not set. Defaulting to a blank string.", key))
+	// If the variable has a default value, then it's not missing
+	if len(variable.DefaultValue) > 0 {
+		return false
 	}
+
+	// If the variable has a alternate/presence value, then it's not missing
+	if len(variable.PresenceValue) > 0 {
+		return false
+	}
+
+	return true
 }
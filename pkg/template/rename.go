@@ -0,0 +1,63 @@
+package template
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	slogctx "github.com/veqryn/slog-context"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// RenameVariable rewrites every reference to the variable [from] in [input] so it points to [to] instead.
+//
+// All parameter expansion forms are supported, such as [$FROM], [${FROM}], [${FROM:-default}]
+// and nested expansions like [${OTHER:-${FROM}}]; everything else in [input] is left untouched.
+//
+// The returned bool indicates if any reference was rewritten.
+func RenameVariable(ctx context.Context, input, from, to string) (string, bool) {
+	type span struct {
+		start, end int
+	}
+
+	var spans []span
+
+	for word, err := range syntax.NewParser(syntax.Variant(syntax.LangBash)).WordsSeq(strings.NewReader(input)) {
+		if err != nil {
+			// Malformed input; rewrite whatever references was found so far.
+			slogctx.Debug(ctx, "template.RenameVariable() stopped on malformed input", slog.Any("error", err))
+
+			break
+		}
+
+		syntax.Walk(word, func(node syntax.Node) bool {
+			param, ok := node.(*syntax.ParamExp)
+			if !ok || param.Param == nil || param.Param.Value != from {
+				return true
+			}
+
+			spans = append(spans, span{
+				start: int(param.Param.Pos().Offset()),
+				end:   int(param.Param.End().Offset()),
+			})
+
+			return true
+		})
+	}
+
+	if len(spans) == 0 {
+		return input, false
+	}
+
+	// Rewrite from the end of the string, so earlier offsets stay valid
+	slices.SortFunc(spans, func(a, b span) int {
+		return b.start - a.start
+	})
+
+	for _, s := range spans {
+		input = input[:s.start] + to + input[s.end:]
+	}
+
+	return input, true
+}
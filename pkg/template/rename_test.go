@@ -0,0 +1,41 @@
+package template_test
+
+import (
+	"testing"
+
+	templatepkg "github.com/jippi/dottie/pkg/template"
+	"github.com/jippi/dottie/pkg/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenameVariable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		changed  bool
+	}{
+		{name: "no references", input: "hello world", expected: "hello world"},
+		{name: "braced", input: "${OLD}", expected: "${NEW}", changed: true},
+		{name: "non braced", input: "$OLD/path", expected: "$NEW/path", changed: true},
+		{name: "default value", input: "${OLD:-fallback}", expected: "${NEW:-fallback}", changed: true},
+		{name: "required", input: "${OLD:?must be set}", expected: "${NEW:?must be set}", changed: true},
+		{name: "nested default", input: "${OTHER:-${OLD}}", expected: "${OTHER:-${NEW}}", changed: true},
+		{name: "multiple", input: "mysql://${OLD}:${PORT}/${OLD}", expected: "mysql://${NEW}:${PORT}/${NEW}", changed: true},
+		{name: "prefix of other key", input: "${OLD_SUFFIX} ${OLD}", expected: "${OLD_SUFFIX} ${NEW}", changed: true},
+		{name: "literal text is untouched", input: "OLD is $OLD", expected: "OLD is $NEW", changed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, changed := templatepkg.RenameVariable(test_helpers.CreateTestContext(t, nil, nil), tt.input, "OLD", "NEW")
+
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.changed, changed)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package template
+
+import "context"
+
+type strictContextKey int
+
+const strictKey strictContextKey = iota
+
+// ContextWithStrict returns a context where [Substitute] returns a [MissingRequiredError] for every
+// unset variable without a default value, instead of expanding it to a blank string
+func ContextWithStrict(ctx context.Context, strict bool) context.Context {
+	return context.WithValue(ctx, strictKey, strict)
+}
+
+// StrictFromContext reports if strict interpolation was enabled with [ContextWithStrict]
+func StrictFromContext(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictKey).(bool)
+
+	return strict
+}
@@ -45,9 +45,23 @@ func Substitute(ctx context.Context, input string, resolver Resolver, accessible
 		AccessibleVariables: accessibleVariables,
 	}
 
+	// In strict mode, unset variables without a default value are collected and reported as errors instead
+	var missing []string
+
+	if StrictFromContext(ctx) {
+		variables := ExtractVariables(ctx, input)
+
+		environment.MissingKeyCallback = func(key string) {
+			if isMissing(variables, key) && !slices.Contains(missing, key) {
+				missing = append(missing, key)
+			}
+		}
+	}
+
 	config := &expand.Config{
 		Env: environment,
-		// Any commands being tried to run will simply be treated as literals
+		// Any commands being tried to run will simply be treated as literals, unless
+		// command substitution is allowed with [ContextWithCommandSubstitution]
 		//
 		// NOTE: the printer _will_ format the code, so that might cause some unwanted side-effects,
 		//       please see https://github.com/mvdan/sh for any issues
@@ -61,7 +75,7 @@ func Substitute(ctx context.Context, input string, resolver Resolver, accessible
 		//    output: $()$
 		CmdSubst: func(writer io.Writer, i *syntax.CmdSubst) error {
 			start := i.Left.Offset()
-			end := i.End().Offset() - 1
+			end := i.End().Offset()
 
 			writer.Write([]byte(input[start:end]))
 
@@ -69,6 +83,22 @@ func Substitute(ctx context.Context, input string, resolver Resolver, accessible
 		},
 	}
 
+	// With command substitution allowed, $(...) is executed instead, and its output used as the value
+	var commandErr error
+
+	if substitution := CommandSubstitutionFromContext(ctx); substitution != nil {
+		config.CmdSubst = func(writer io.Writer, node *syntax.CmdSubst) error {
+			variables := map[string]string{}
+			if accessibleVariables != nil {
+				variables = accessibleVariables()
+			}
+
+			commandErr = substitution.run(ctx, writer, input, node, variables)
+
+			return commandErr
+		}
+	}
+
 	// Parse template into Shell words
 	words, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Document(strings.NewReader(input))
 	if err != nil {
@@ -97,6 +127,9 @@ func Substitute(ctx context.Context, input string, resolver Resolver, accessible
 		target := &expand.UnsetParameterError{}
 
 		switch {
+		case commandErr != nil:
+			combinedErrors = multierr.Append(combinedErrors, commandErr)
+
 		case errors.As(err, target):
 			combinedErrors = multierr.Append(combinedErrors, &MissingRequiredError{
 				Variable: target.Node.Param.Value,
@@ -108,6 +141,13 @@ func Substitute(ctx context.Context, input string, resolver Resolver, accessible
 		}
 	}
 
+	for _, name := range missing {
+		combinedErrors = multierr.Append(combinedErrors, &MissingRequiredError{
+			Variable: name,
+			Reason:   "strict interpolation requires it to be set, or to have a default value",
+		})
+	}
+
 	slogctx.Debug(ctx, "template.Substitute output", tui.StringDump("output", result))
 
 	return result, combinedErrors
@@ -18,11 +18,13 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	templatepkg "github.com/jippi/dottie/pkg/template"
 	"github.com/jippi/dottie/pkg/test_helpers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
 )
 
 var defaults = map[string]string{
@@ -864,3 +866,79 @@ func TestExtractVariables(t *testing.T) {
 		})
 	}
 }
+
+func TestStrictModeFailsOnUnsetVariables(t *testing.T) {
+	t.Parallel()
+
+	ctx := templatepkg.ContextWithStrict(test_helpers.CreateTestContext(t, nil, nil), true)
+
+	_, err := templatepkg.Substitute(ctx, "ok ${UNSET_VAR} ${UNSET_VAR} $OTHER_UNSET_VAR", defaultMapping, accessibleVariables)
+	require.Error(t, err)
+
+	var missing *templatepkg.MissingRequiredError
+
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, "UNSET_VAR", missing.Variable)
+	assert.Len(t, multierr.Errors(err), 2)
+	assert.Contains(t, err.Error(), "OTHER_UNSET_VAR")
+}
+
+func TestStrictModeAllowsDefaults(t *testing.T) {
+	t.Parallel()
+
+	ctx := templatepkg.ContextWithStrict(test_helpers.CreateTestContext(t, nil, nil), true)
+
+	for _, template := range []string{"ok ${UNSET_VAR:-def}", "ok ${UNSET_VAR-def}", "ok ${UNSET_VAR:+presence_value}", "ok ${FOO}"} {
+		_, err := templatepkg.Substitute(ctx, template, defaultMapping, accessibleVariables)
+		require.NoError(t, err, template)
+	}
+}
+
+func TestCommandSubstitutionIsVerbatimByDefault(t *testing.T) {
+	t.Parallel()
+
+	result, err := templatepkg.Substitute(test_helpers.CreateTestContext(t, nil, nil), "rev $(echo $FOO)", defaultMapping, accessibleVariables)
+	require.NoError(t, err)
+	assert.Equal(t, "rev $(echo $FOO)", result)
+}
+
+func TestCommandSubstitutionRunsCommands(t *testing.T) {
+	t.Parallel()
+
+	substitution := &templatepkg.CommandSubstitution{}
+	ctx := templatepkg.ContextWithCommandSubstitution(test_helpers.CreateTestContext(t, nil, nil), substitution)
+
+	result, err := templatepkg.Substitute(ctx, "rev $(echo $FOO) `echo ${JSON}`", defaultMapping, accessibleVariables)
+	require.NoError(t, err)
+	assert.Equal(t, `rev first {"json":2}`, result)
+	assert.Equal(t, []string{"echo $FOO", "echo ${JSON}"}, substitution.Executed())
+}
+
+func TestCommandSubstitutionUsesRestrictedEnvironment(t *testing.T) {
+	t.Setenv("DOTTIE_COMMAND_SECRET", "secret")
+
+	ctx := templatepkg.ContextWithCommandSubstitution(test_helpers.CreateTestContext(t, nil, nil), &templatepkg.CommandSubstitution{})
+
+	result, err := templatepkg.Substitute(ctx, "[$(echo $DOTTIE_COMMAND_SECRET)]", defaultMapping, accessibleVariables)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", result)
+}
+
+func TestCommandSubstitutionFailures(t *testing.T) {
+	t.Parallel()
+
+	ctx := templatepkg.ContextWithCommandSubstitution(test_helpers.CreateTestContext(t, nil, nil), &templatepkg.CommandSubstitution{Timeout: 50 * time.Millisecond})
+
+	_, err := templatepkg.Substitute(ctx, "$(sleep 5)", defaultMapping, accessibleVariables)
+	require.ErrorContains(t, err, "command substitution [ sleep 5 ] timed out after 50ms")
+
+	_, err = templatepkg.Substitute(ctx, "$(false)", defaultMapping, accessibleVariables)
+	require.ErrorContains(t, err, "command substitution [ false ] failed: exit status 1")
+}
+
+func TestCommandVariables(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"NAME", "HOST"}, templatepkg.CommandVariables(`$OUTSIDE "$(echo $NAME ${HOST:-x} $NAME)"`))
+	assert.Empty(t, templatepkg.CommandVariables("$OUTSIDE"))
+}
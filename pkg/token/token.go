@@ -1,14 +1,19 @@
 package token
 
 type Token struct {
-	Type       Type
-	Literal    string
-	Offset     int
-	Length     int
-	LineNumber uint
-	Commented  bool
-	Quote      Quote
-	Annotation *Annotation
+	Type        Type
+	Literal     string
+	Offset      int
+	Length      int
+	LineNumber  uint
+	Column      int // 1-based column (in characters) of the first character of the token
+	EndColumn   int // 1-based column (in characters) right after the last character of the token
+	StartOffset int // Byte offset of the first character of the token in the input
+	EndOffset   int // Byte offset right after the last character of the token in the input
+	Commented   bool
+	Exported    bool
+	Quote       Quote
+	Annotation  *Annotation
 }
 
 func New(t Type, options ...Option) Token {
@@ -17,6 +17,7 @@ const (
 	GroupBanner       // # -- ### (3 or more hashtags)
 	Comment           // # -- # <anything>
 	CommentAnnotation // # -- # @<name> <value>
+	InlineComment     // # -- KEY=VALUE # <anything>
 	Assign            // = -- KEY=VALUE
 
 	//
@@ -41,6 +42,7 @@ var tokens = []string{
 	GroupBanner:       "GROUP_HEADER",
 	Comment:           "COMMENT",
 	CommentAnnotation: "COMMENT_ANNOTATION",
+	InlineComment:     "INLINE_COMMENT",
 	Assign:            "ASSIGN",
 
 	//
@@ -8,11 +8,13 @@ import (
 	"os"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/huh"
 	"github.com/go-playground/validator/v10"
 	"github.com/jippi/dottie/pkg"
 	"github.com/jippi/dottie/pkg/ast"
+	"github.com/jippi/dottie/pkg/render"
 	"github.com/jippi/dottie/pkg/tui"
 )
 
@@ -43,6 +45,8 @@ func Explain(ctx context.Context, doc *ast.Document, inputError any, assignment
 		if showField {
 			danger.Print("  ", assignment.Name)
 			dark.Println(" (", assignment.Position, ")")
+
+			printValueUnderline(ctx, writer, assignment)
 		}
 
 		for _, e := range err.Errors() {
@@ -74,6 +78,8 @@ func Explain(ctx context.Context, doc *ast.Document, inputError any, assignment
 			dark.Print(" (", assignment.Position, ")")
 
 			dark.Println()
+
+			printValueUnderline(ctx, writer, assignment)
 		}
 
 		for _, rule := range err {
@@ -131,6 +137,27 @@ func Explain(ctx context.Context, doc *ast.Document, inputError any, assignment
 	return buff.String()
 }
 
+// printValueUnderline prints the assignment line with its value underlined.
+//
+// Nothing is printed if the location of the value is unknown, or if the value spans multiple lines.
+func printValueUnderline(ctx context.Context, writer tui.Writer, assignment *ast.Assignment) {
+	if assignment.Position.Column == 0 || assignment.ValueSpan.Column == 0 || !assignment.ValueSpan.IsSingleLine() {
+		return
+	}
+
+	line := strings.TrimRight(render.PlainOutput{}.Assignment(ctx, assignment, render.Settings{}).String(), "\n")
+
+	// The rendered line must match the source, otherwise the underline would be misplaced
+	if strings.Contains(line, "\n") || utf8.RuneCountInString(line) != assignment.ValueSpan.EndColumn-assignment.Position.Column {
+		return
+	}
+
+	width := max(assignment.ValueSpan.EndColumn-assignment.ValueSpan.Column, 1)
+
+	writer.NoColor().Println("    " + line)
+	writer.Danger().Println("    " + strings.Repeat(" ", assignment.ValueSpan.Column-assignment.Position.Column) + strings.Repeat("^", width))
+}
+
 type messageSegment struct {
 	text        string
 	highlighted bool
@@ -369,7 +396,7 @@ func AskToSetValue(ctx context.Context, doc *ast.Document, assignment *ast.Assig
 
 	assignment.SetLiteral(ctx, value)
 
-	if err := pkg.Save(ctx, assignment.Position.File, doc); err != nil {
+	if err := saveValue(ctx, assignment.Position.File, assignment.Name, value); err != nil {
 		stderr.Danger().Println("    Could not update key with value [" + value + "]: " + err.Error())
 
 		return
@@ -377,3 +404,27 @@ func AskToSetValue(ctx context.Context, doc *ast.Document, assignment *ast.Assig
 
 	stderr.Success().Println("    Successfully updated key with value [" + value + "]")
 }
+
+// saveValue sets the KEY [name] to [value] in [filename]. The file is only locked once there is something to save,
+// and loaded again under the lock, so changes made while the user was prompted are not overwritten.
+func saveValue(ctx context.Context, filename, name, value string) error {
+	unlock, err := pkg.Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	doc, err := pkg.Load(ctx, filename)
+	if err != nil {
+		return err
+	}
+
+	assignment := doc.Get(name)
+	if assignment == nil {
+		return fmt.Errorf("key [ %s ] was removed from the file", name)
+	}
+
+	assignment.SetLiteral(ctx, value)
+
+	return pkg.Save(ctx, filename, doc)
+}